@@ -2,8 +2,10 @@ package auditlogsreceiver
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"strconv"
 	"sync"
 	"syscall"
@@ -14,7 +16,8 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
-	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 
 	"github.com/castai/audit-logs-receiver/audit-logs/storage"
@@ -31,37 +34,162 @@ type filters struct {
 	clusterID *string
 }
 
+// hashChain keeps head of the chain of every route ("" when routing is not configured), as records of every route are
+// exported to a destination of their own.
+type hashChain struct {
+	heads map[string]storage.ChainHead
+}
+
 type auditLogsReceiver struct {
 	logger       *zap.Logger
 	pollInterval time.Duration
-
-	pageLimit int
-	filter    filters
+	scheduler    *pollScheduler
+
+	// organization is set when receiver polls one of several configured organizations.
+	organization    string
+	pageLimit       int
+	batchPages      int
+	maxResponseSize int64
+	filter          filters
+	itemFilter      *expressionFilter
+	initiators      *initiatorFilter
+	labels          *labelFilter
+	sampler         *sampler
+	router          *router
+	redactor        *redactor
+	lokiLabels      *lokiLabels
+	profile         outputProfile
+	chain           *hashChain
+
+	// processMu serializes processing of audit logs, which are received both by polling and via webhook.
+	processMu sync.Mutex
+	delivered *deliveredIDs
+
+	webhook       *WebhookConfig
+	webhookSecret []byte
+	webhookServer *http.Server
+	telemetry     component.TelemetrySettings
+	buildInfo     component.BuildInfo
 
 	wg          *sync.WaitGroup
 	stopPolling context.CancelFunc
+	// draining is closed by startDraining on shutdown, so polling stops once the current page is delivered.
+	draining      <-chan struct{}
+	startDraining context.CancelFunc
 
+	api      API
 	storage  storage.Storage
 	rest     *resty.Client
 	consumer consumer.Logs
 }
 
-func (a *auditLogsReceiver) Start(_ context.Context, _ component.Host) error {
+func (a *auditLogsReceiver) Start(ctx context.Context, host component.Host) error {
 	a.logger.Debug("starting audit logs receiver")
 
+	if a.rest == nil {
+		err := a.startRestClient(ctx, host)
+		if err != nil {
+			return fmt.Errorf("creating audit logs api client: %w", err)
+		}
+	}
+
+	if a.webhook != nil {
+		err := a.startWebhook(ctx, host)
+		if err != nil {
+			return fmt.Errorf("starting audit logs webhook: %w", err)
+		}
+	}
+
 	// According to Component interface, Start function should not reuse context for background tasks.
-	ctx, cancel := context.WithCancel(context.Background())
+	pollCtx, cancel := context.WithCancel(context.Background())
 	a.stopPolling = cancel
+	drainCtx, startDraining := context.WithCancel(context.Background())
+	a.draining = drainCtx.Done()
+	a.startDraining = startDraining
 	a.wg.Add(1)
-	go a.startPolling(ctx)
+	go a.startPolling(pollCtx)
 
 	return nil
 }
 
-func (a *auditLogsReceiver) Shutdown(_ context.Context) error {
+func (a *auditLogsReceiver) Shutdown(ctx context.Context) error {
 	a.logger.Debug("shutting down audit logs receiver")
+
+	var err error
+	if a.webhookServer != nil {
+		err = a.webhookServer.Shutdown(ctx)
+	}
+
+	// Polling is given a chance to deliver the current page and persist the checkpoint; in-flight work is
+	// cancelled only when it does not finish before the deadline of the shutdown context.
+	if a.startDraining != nil {
+		a.startDraining()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.logger.Warn("audit logs receiver did not finish the current page before shutdown deadline, cancelling it")
+		a.stopPolling()
+		<-done
+	}
 	a.stopPolling()
-	a.wg.Wait()
+
+	return err
+}
+
+func (a *auditLogsReceiver) startRestClient(ctx context.Context, host component.Host) error {
+	httpClient, err := a.api.ToClient(ctx, host, a.telemetry)
+	if err != nil {
+		return err
+	}
+
+	a.rest = newRestyClient(a.api, httpClient, a.buildInfo)
+	return nil
+}
+
+func (a *auditLogsReceiver) startWebhook(ctx context.Context, host component.Host) error {
+	listener, err := a.webhook.ToListener(ctx)
+	if err != nil {
+		return err
+	}
+
+	path := a.webhook.Path
+	if path == "" {
+		path = defaultWebhookPath
+	}
+
+	handler := &webhookHandler{
+		logger: a.logger,
+		path:   path,
+		secret: a.webhookSecret,
+		process: func(ctx context.Context, auditLogsMap map[string]interface{}) error {
+			_, err := a.processAuditLogs(ctx, auditLogsMap)
+			return err
+		},
+	}
+	a.webhookServer, err = a.webhook.ToServer(ctx, host, a.telemetry, handler)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		a.logger.Info("audit logs webhook is listening", zap.String("endpoint", listener.Addr().String()), zap.String("path", path))
+		err := a.webhookServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("audit logs webhook server stopped", zap.Error(err))
+		}
+	}()
 
 	return nil
 }
@@ -69,11 +197,17 @@ func (a *auditLogsReceiver) Shutdown(_ context.Context) error {
 func (a *auditLogsReceiver) startPolling(ctx context.Context) {
 	defer a.wg.Done()
 
-	t := time.NewTicker(a.pollInterval)
+	scheduler := a.scheduler
+	if scheduler == nil {
+		scheduler = newPollScheduler(a.pollInterval, AdaptivePollingConfig{})
+	}
+
+	// Timer is reset after every poll, as the interval may change from one poll to another.
+	t := time.NewTimer(a.pollInterval)
 	defer t.Stop()
 
 	for {
-		err := a.poll(ctx, func() {
+		stats, err := a.poll(ctx, func() {
 			// Stop function is called in case of critical errors (error that cannot be restored from).
 			a.stopPolling()
 
@@ -87,16 +221,27 @@ func (a *auditLogsReceiver) startPolling(ctx context.Context) {
 			a.logger.Error("there was an error during the poll", zap.Error(err))
 		}
 
+		interval := scheduler.next(stats, err)
+		a.logger.Debug("scheduling next poll", zap.Int("records", stats.records), zap.Duration("interval", interval))
+		t.Reset(interval)
+
+		// Checked before waiting, as the next poll may be due immediately and select picks ready cases at random.
+		if a.isDraining() {
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			return
+		case <-a.draining:
+			return
 		case <-t.C:
 			continue
 		}
 	}
 }
 
-func (a *auditLogsReceiver) poll(ctx context.Context, stopFunc func()) error {
+func (a *auditLogsReceiver) poll(ctx context.Context, stopFunc func()) (stats pollStats, err error) {
 	// It is OK to have long durations (to - from) as backend will handle it through pagination & page limit.
 	pollData := a.storage.Get()
 
@@ -106,15 +251,18 @@ func (a *auditLogsReceiver) poll(ctx context.Context, stopFunc func()) error {
 		pollData.NextCheckPoint = pollData.ToDate
 
 		// Saving state, as fromDate and toDate are fixed from now on.
-		err := a.storage.Save(pollData)
+		err = a.savePollData(pollData)
 		if err != nil {
-			return err
+			return stats, err
 		}
 	}
 
 	// Logging polling data, which is helpful for debugging.
 	a.logger.Debug("polling for audit logs", zap.Any("poll_data", pollData))
 
+	// Pages are consumed in batches of batchPages; position is saved only once a batch is consumed, so pages of a batch
+	// which was not consumed are polled again.
+	batch := &logsBatch{}
 	var queryParams map[string]string
 	for {
 		if queryParams == nil {
@@ -131,89 +279,253 @@ func (a *auditLogsReceiver) poll(ctx context.Context, stopFunc func()) error {
 		resp, err := a.rest.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
+			SetDoNotParseResponse(true).
 			Get("")
 		if err != nil {
-			return err
+			return stats, err
 		}
 		if resp.StatusCode() > 399 {
+			snippet := readSnippet(resp.RawBody())
+			resp.RawBody().Close()
 			switch resp.StatusCode() {
 			case 401, 403:
 				// Authentication error is treated as critical error hence calling a stop function.
 				stopFunc()
-				return fmt.Errorf("invalid api access key, response code: %d", resp.StatusCode())
+				return stats, fmt.Errorf("invalid api access key, response code: %d", resp.StatusCode())
 			default:
-				a.logger.Warn("unexpected response from audit logs api:", zap.Any("response_code", resp.StatusCode()))
-				return fmt.Errorf("got non 200 status code %d", resp.StatusCode())
+				a.logger.Warn("unexpected response from audit logs api:", zap.Any("response_code", resp.StatusCode()), zap.String("body", a.redactor.redactSnippet(snippet)))
+				return stats, fmt.Errorf("got non 200 status code %d", resp.StatusCode())
 			}
 		}
 
-		auditLogsMap, lastAuditLogTimestamp, err := a.processResponseBody(ctx, resp.Body())
+		p, err := a.processResponseBody(ctx, batch, resp.RawBody())
+		resp.RawBody().Close()
 		if err != nil {
-			return err
+			return stats, err
+		}
+
+		stats.records += p.items
+		if p.items >= a.pageLimit {
+			stats.limitReached = true
+		}
+
+		flushed := p.nextCursor == "" || batch.pages >= a.batchPages
+		if flushed && p.nextCursor != "" {
+			stats.limitReached = true
+		}
+		if flushed {
+			err = a.flush(ctx, batch)
+			if err != nil {
+				return stats, err
+			}
 		}
 
 		// if lastAuditLogTimestamp is not returned, then there were no valid items found in the response
-		if lastAuditLogTimestamp == nil {
+		if p.lastAuditLogTimestamp == nil {
 			break
 		}
 
 		// Shifting ToDate towards the current check point with every processed page.
-		pollData.ToDate = lastAuditLogTimestamp
-		err = a.storage.Save(pollData)
-		if err != nil {
-			return err
+		pollData.ToDate = p.lastAuditLogTimestamp
+		if flushed {
+			err = a.savePollData(pollData)
+			if err != nil {
+				return stats, err
+			}
 		}
 
-		c, ok := auditLogsMap["nextCursor"]
-		if !ok {
-			// Cursor data is not provided, so it is the last page.
+		if p.nextCursor == "" {
 			break
 		}
 
-		// Creating query parameters based on cursor as there is more data to be fetched.
-		cursor, ok := c.(string)
-		if !ok {
-			a.logger.Warn("invalid cursor type is returned, skipping")
-			break
-		}
-		if cursor == "" {
-			break
+		// Stopping between pages when shutting down; pages of the batch are consumed and their checkpoint is saved
+		// first, so polling resumes from it after a restart.
+		if a.isDraining() {
+			if batch.pages > 0 {
+				err = a.flush(ctx, batch)
+				if err != nil {
+					return stats, err
+				}
+				err = a.savePollData(pollData)
+				if err != nil {
+					return stats, err
+				}
+			}
+			a.logger.Info("stopping polling after the current page due to shutdown", zap.Any("poll_data", pollData))
+			return stats, nil
 		}
 
 		queryParams = map[string]string{
 			"page.limit":  strconv.Itoa(a.pageLimit),
-			"page.cursor": cursor,
+			"page.cursor": p.nextCursor,
 		}
 	}
 
+	// Pages without valid items end polling, yet pages of the batch before them are still to be consumed.
+	err = a.flush(ctx, batch)
+	if err != nil {
+		return stats, err
+	}
+
 	// Storing state about Audit Logs export position.
 	pollData.CheckPoint = *pollData.NextCheckPoint
 	pollData.ToDate = nil
 	pollData.NextCheckPoint = nil
-	err := a.storage.Save(pollData)
+	err = a.savePollData(pollData)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+func (a *auditLogsReceiver) isDraining() bool {
+	select {
+	case <-a.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// savePollData saves poll position together with heads of chains.
+func (a *auditLogsReceiver) savePollData(pollData storage.PollData) error {
+	a.processMu.Lock()
+	defer a.processMu.Unlock()
+
+	return a.savePollDataLocked(pollData)
+}
+
+func (a *auditLogsReceiver) savePollDataLocked(pollData storage.PollData) error {
+	// Heads of chains are persisted together with the position, so chains continue after a restart. Head of the
+	// default route is kept in one of the fields only, so a head saved before routing was toggled does not replace it.
+	if a.chain != nil {
+		if a.router == nil {
+			pollData.Chain = lo.ToPtr(a.chain.heads[""])
+			// Heads of routes are kept for when routing is enabled again.
+			pollData.Chains = lo.OmitByKeys(a.chain.heads, []string{""})
+			if len(pollData.Chains) == 0 {
+				pollData.Chains = nil
+			}
+		} else {
+			pollData.Chain = nil
+			pollData.Chains = maps.Clone(a.chain.heads)
+		}
+	}
+
+	return a.storage.Save(pollData)
+}
+
+// keep tells if the item passes filters. Initiator and label filters go first, so expression rules count matches
+// among items of wanted initiators and labels only.
+func (a *auditLogsReceiver) keep(ctx context.Context, item map[string]interface{}, counts *batchCounts) bool {
+	return a.initiators.keep(item, counts) && a.labels.keep(item, counts) && a.itemFilter.keep(ctx, item, counts)
+}
+
+func (a *auditLogsReceiver) setMeterProvider(meterProvider metric.MeterProvider) error {
+	err := a.itemFilter.setMeterProvider(meterProvider)
 	if err != nil {
 		return err
 	}
+	err = a.initiators.setMeterProvider(meterProvider)
+	if err != nil {
+		return err
+	}
+	err = a.labels.setMeterProvider(meterProvider)
+	if err != nil {
+		return err
+	}
+	return a.sampler.setMeterProvider(meterProvider)
+}
 
-	return nil
+// metricAttributes are attributes of all metrics of the receiver; in multi-organization mode, metrics of every
+// organization are told apart by castai.organization attribute.
+func (a *auditLogsReceiver) metricAttributes() []attribute.KeyValue {
+	if a.organization == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String(organizationAttribute, a.organization)}
 }
 
-func (a *auditLogsReceiver) processResponseBody(ctx context.Context, body []byte) (map[string]interface{}, *time.Time, error) {
-	var auditLogsMap map[string]interface{}
-	err := json.Unmarshal(body, &auditLogsMap)
+// processAuditLogs converts audit logs and consumes them at once. Audit logs received via webhook move chains forward
+// between polls, so heads of chains are saved right away; otherwise their sequence numbers would be reused after a
+// crash.
+func (a *auditLogsReceiver) processAuditLogs(ctx context.Context, auditLogsMap map[string]interface{}) (*time.Time, error) {
+	a.processMu.Lock()
+	defer a.processMu.Unlock()
+
+	batch := &logsBatch{}
+	lastAuditLogTimestamp, err := a.appendAuditLogs(ctx, batch, auditLogsMap)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unexpected body in response: %v", string(body))
+		return nil, err
 	}
 
-	lastAuditLogTimestamp, err := a.processAuditLogs(ctx, auditLogsMap)
+	err = a.flushLocked(ctx, batch)
 	if err != nil {
-		return nil, nil, fmt.Errorf("processing audit logs items: %w", err)
+		return nil, err
+	}
+
+	if a.chain != nil {
+		// Position last saved by polling is kept; polling saves it under the same lock, so it is never overwritten by
+		// an older one.
+		err = a.savePollDataLocked(a.storage.Get())
+		if err != nil {
+			return nil, fmt.Errorf("saving chain heads: %w", err)
+		}
+	}
+
+	return lastAuditLogTimestamp, nil
+}
+
+// flush consumes audit logs of the batch which were not consumed yet.
+func (a *auditLogsReceiver) flush(ctx context.Context, batch *logsBatch) error {
+	a.processMu.Lock()
+	defer a.processMu.Unlock()
+
+	return a.flushLocked(ctx, batch)
+}
+
+func (a *auditLogsReceiver) flushLocked(ctx context.Context, batch *logsBatch) error {
+	if batch.pages == 0 {
+		return nil
+	}
+
+	defer batch.reset()
+
+	var chainHeads map[string]storage.ChainHead
+	if a.chain != nil {
+		var err error
+		chainHeads, err = batch.link(a.chain.heads)
+		if err != nil {
+			return fmt.Errorf("linking audit logs to hash chain: %w", err)
+		}
+	}
+
+	if batch.logs.LogRecordCount() > 0 {
+		if err := a.consumer.ConsumeLogs(ctx, batch.logs); err != nil {
+			return fmt.Errorf("consuming logs: %w", err)
+		}
 	}
 
-	return auditLogsMap, lastAuditLogTimestamp, nil
+	// Chain, filter metrics and sampling limits are advanced only when logs were consumed, otherwise the same items are
+	// processed again during the next poll.
+	if a.chain != nil {
+		a.chain.heads = chainHeads
+	}
+	batch.counts.record(ctx, a.metricAttributes()...)
+	a.sampler.commit(&batch.counts)
+	for _, id := range batch.ids {
+		a.delivered.add(id)
+	}
+
+	return nil
 }
 
-func (a *auditLogsReceiver) processAuditLogs(ctx context.Context, auditLogsMap map[string]interface{}) (lastAuditLogTimestamp *time.Time, err error) {
+// appendAuditLogs converts audit logs to log records of the batch; records are grouped by resource, so a page of
+// audit logs usually makes a single resource and scope.
+func (a *auditLogsReceiver) appendAuditLogs(ctx context.Context, batch *logsBatch, auditLogsMap map[string]interface{}) (lastAuditLogTimestamp *time.Time, err error) {
+	batch.addPage()
+
 	its, ok := auditLogsMap["items"]
 	if !ok {
 		a.logger.Warn("no audit logs items found in the response, skipping", zap.Any("response", auditLogsMap))
@@ -222,62 +534,113 @@ func (a *auditLogsReceiver) processAuditLogs(ctx context.Context, auditLogsMap m
 
 	items, ok := its.([]interface{})
 	if !ok {
-		a.logger.Warn("invalid items type in the response, skipping", zap.Any("items", its))
+		// Items are not redacted yet, so only their type is logged.
+		a.logger.Warn("invalid items type in the response, skipping", zap.String("items_type", fmt.Sprintf("%T", its)))
 		return
 	}
 
-	logs := plog.NewLogs()
 	for _, it := range items {
-		item, ok := it.(map[string]interface{})
-		if !ok {
-			a.logger.Warn("invalid item type among items, skipping", zap.Any("item", it))
-			continue
+		auditLogTimestamp, err := a.appendAuditLog(ctx, batch, it)
+		if err != nil {
+			return nil, err
 		}
+		if auditLogTimestamp != nil {
+			lastAuditLogTimestamp = auditLogTimestamp
+		}
+	}
+	return
+}
 
-		// Dumping content of the Audit Logs to the console.
-		a.logger.Info("processing new audit log", zap.Any("data", item))
+// appendAuditLog converts an audit log to a log record of the batch. It returns time of the audit log which moves poll
+// position, or nil when the item is not valid.
+func (a *auditLogsReceiver) appendAuditLog(ctx context.Context, batch *logsBatch, it interface{}) (*time.Time, error) {
+	item, ok := it.(map[string]interface{})
+	if !ok {
+		a.logger.Warn("invalid item type among items, skipping", zap.Any("item", it))
+		return nil, nil
+	}
 
-		attributesMap := map[string]interface{}{
-			"id":          item["id"],
-			"eventType":   item["eventType"],
-			"initiatedBy": item["initiatedBy"],
-			"labels":      item["labels"],
-			"event":       item["event"],
+	id, _ := item["id"].(string)
+	// Audit logs which were already delivered (for example, via webhook), are filtered or sampled out are skipped,
+	// yet their time still moves poll position. Sampling goes last, so rate limits count exported audit logs only.
+	skip := a.delivered.contains(id) || !a.keep(ctx, item, &batch.counts)
+	var samplingRate int
+	if !skip {
+		var sampled bool
+		samplingRate, sampled = a.sampler.sample(item, &batch.counts)
+		skip = !sampled
+	}
+	if skip {
+		if str, ok := item["time"].(string); ok {
+			if auditLogTimestamp, err := time.Parse(timestampLayout, str); err == nil {
+				return &auditLogTimestamp, nil
+			}
 		}
+		return nil, nil
+	}
+	batch.ids = append(batch.ids, id)
 
-		resourceLog := logs.ResourceLogs().AppendEmpty()
-		logRecord := resourceLog.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	// Actor type and route are derived before redaction, as redaction may remove values they depend on.
+	actor := actorType(item)
+	routeName := a.router.route(item)
 
-		// It may fail due to an invalid type used in attributesMap; in that case, nothing can be done so entry is skipped.
-		err = logRecord.Attributes().FromRaw(attributesMap)
-		if err != nil {
-			return nil, err
-		}
+	// Redacting before anything else, so sensitive values do not leak even into receiver's own logs.
+	a.redactor.redact(item)
 
-		str, ok := item["time"].(string)
-		if !ok {
-			a.logger.Warn("invalid item's time type, skipping", zap.Any("time", str))
-			continue
-		}
+	// Dumping content of the Audit Logs to the console.
+	a.logger.Info("processing new audit log", zap.Any("data", item))
+
+	attributesMap := map[string]interface{}{
+		"id":          item["id"],
+		"eventType":   item["eventType"],
+		"initiatedBy": item["initiatedBy"],
+		"labels":      item["labels"],
+		"event":       item["event"],
+	}
+	a.lokiLabels.apply(attributesMap, item, actor)
+	if samplingRate > 0 {
+		attributesMap[samplingRateAttribute] = samplingRate
+	}
+	// Route is set on the record as well, as every route has its own hash chain.
+	if routeName != "" {
+		attributesMap[routeAttribute] = routeName
+	}
+
+	resource, records := batch.records(a, routeName)
+	logRecord := records.AppendEmpty()
+
+	// It may fail due to an invalid type used in attributesMap; in that case, nothing can be done so entry is skipped.
+	err := logRecord.Attributes().FromRaw(attributesMap)
+	if err != nil {
+		return nil, err
+	}
 
-		var auditLogTimestamp time.Time
-		auditLogTimestamp, err = time.Parse(timestampLayout, str)
+	if a.profile != nil {
+		err = a.profile.apply(resource, logRecord, item)
 		if err != nil {
-			a.logger.Warn("item's time was not recognized, skipping", zap.Any("time", str), zap.Error(err))
-			continue
+			return nil, fmt.Errorf("applying output profile: %w", err)
 		}
-		lastAuditLogTimestamp = &auditLogTimestamp
+	}
 
-		observedTime := pcommon.NewTimestampFromTime(time.Now())
-		logRecord.SetObservedTimestamp(observedTime)
-		logRecord.SetTimestamp(pcommon.NewTimestampFromTime(auditLogTimestamp))
+	str, ok := item["time"].(string)
+	if !ok {
+		a.logger.Warn("invalid item's time type, skipping", zap.Any("time", str))
+		return nil, nil
 	}
 
-	if logs.LogRecordCount() > 0 {
-		if err = a.consumer.ConsumeLogs(ctx, logs); err != nil {
-			return nil, fmt.Errorf("consuming logs: %w", err)
-		}
+	auditLogTimestamp, parseErr := time.Parse(timestampLayout, str)
+	if parseErr != nil {
+		a.logger.Warn("item's time was not recognized, skipping", zap.Any("time", str), zap.Error(parseErr))
+		return nil, nil
 	}
 
-	return
+	observedTime := pcommon.NewTimestampFromTime(time.Now())
+	logRecord.SetObservedTimestamp(observedTime)
+	if a.profile != nil {
+		logRecord.SetTimestamp(pcommon.NewTimestampFromTime(a.profile.timestamp(auditLogTimestamp)))
+	} else {
+		logRecord.SetTimestamp(pcommon.NewTimestampFromTime(auditLogTimestamp))
+	}
+
+	return &auditLogTimestamp, nil
 }
@@ -2,7 +2,11 @@ package auditlogsreceiver
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
@@ -12,9 +16,15 @@ import (
 	"github.com/google/uuid"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configauth"
+	"go.opentelemetry.io/collector/config/configopaque"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
 
+	"github.com/castai/audit-logs-receiver/audit-logs/chain"
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
 	"github.com/castai/audit-logs-receiver/audit-logs/storage"
 	mock_storage "github.com/castai/audit-logs-receiver/audit-logs/storage/mock"
 )
@@ -66,7 +76,7 @@ func TestPoll(t *testing.T) {
 			},
 			PageLimit: 11,
 		}
-		rest := newRestyClient(&restConfig)
+		rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
 		httpmock.ActivateNonDefault(rest.GetClient())
 		defer httpmock.Reset()
 
@@ -87,7 +97,7 @@ func TestPoll(t *testing.T) {
 			storage: storageMock,
 			rest:    rest,
 		}
-		err := receiver.poll(ctx, nil)
+		_, err := receiver.poll(ctx, nil)
 		r.NoError(err)
 	})
 
@@ -158,7 +168,7 @@ func TestPoll(t *testing.T) {
 			},
 			PageLimit: 11,
 		}
-		rest := newRestyClient(&restConfig)
+		rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
 		httpmock.ActivateNonDefault(rest.GetClient())
 		defer httpmock.Reset()
 
@@ -180,7 +190,7 @@ func TestPoll(t *testing.T) {
 			rest:     rest,
 			consumer: consumerMock,
 		}
-		err := receiver.poll(ctx, nil)
+		_, err := receiver.poll(ctx, nil)
 		r.NoError(err)
 	})
 
@@ -265,7 +275,7 @@ func TestPoll(t *testing.T) {
 			},
 			PageLimit: 2,
 		}
-		rest := newRestyClient(&restConfig)
+		rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
 		httpmock.ActivateNonDefault(rest.GetClient())
 		defer httpmock.Reset()
 
@@ -316,11 +326,63 @@ func TestPoll(t *testing.T) {
 			rest:      rest,
 			consumer:  consumerMock,
 		}
-		err := receiver.poll(ctx, nil)
+		stats, err := receiver.poll(ctx, nil)
 		r.NoError(err)
+		// The first page was full, so a backlog was being worked through even though the last page was not.
+		r.Equal(pollStats{records: 3, limitReached: true}, stats)
 	})
 
-	t.Run("should cancel work immediately after shutdown is called", func(t *testing.T) {
+	t.Run("when a multi-page backlog is polled then the next poll is immediate", func(t *testing.T) {
+		r := require.New(t)
+
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		storageMock := mock_storage.NewMockStorage(mockCtrl)
+		storageMock.EXPECT().Get().Return(storage.PollData{CheckPoint: time.Now().Add(-time.Hour)})
+		storageMock.EXPECT().Save(gomock.Any()).AnyTimes()
+
+		rest := newRestyClient(API{Url: "https://api.cast.ai", Key: uuid.NewString()}, &http.Client{}, component.BuildInfo{})
+		httpmock.ActivateNonDefault(rest.GetClient())
+		defer httpmock.Reset()
+
+		// Two full pages followed by a partial one, so the last page alone does not reveal the backlog.
+		lastLogTimestamp := time.Now().Add(-time.Second)
+		pages := []string{
+			newResponseWithTwoItem(lastLogTimestamp, "page-2"),
+			newResponseWithTwoItem(lastLogTimestamp.Add(-time.Second), "page-3"),
+			newResponseWithOneItem(lastLogTimestamp.Add(-2 * time.Second)),
+		}
+		page := 0
+		httpmock.RegisterResponder(http.MethodGet, `=~^https:\/\/api\.cast\.ai/v1/audit.?`,
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(http.StatusOK, pages[page])
+				page++
+				return resp, nil
+			})
+
+		receiver := auditLogsReceiver{
+			logger:     zap.NewNop(),
+			pageLimit:  2,
+			batchPages: 1,
+			storage:    storageMock,
+			rest:       rest,
+			consumer:   logsConsumerMock{ConsumeLogsFunc: func(plog.Logs) error { return nil }},
+		}
+		stats, err := receiver.poll(context.Background(), nil)
+		r.NoError(err)
+		r.Equal(5, stats.records)
+		r.True(stats.limitReached)
+
+		scheduler := newPollScheduler(10*time.Second, AdaptivePollingConfig{
+			Enabled:     true,
+			MinInterval: time.Second,
+			MaxInterval: time.Minute,
+		})
+		r.Zero(scheduler.next(stats, err))
+	})
+
+	t.Run("should cancel work after shutdown deadline is exceeded", func(t *testing.T) {
 		r := require.New(t)
 		ctx := context.Background()
 
@@ -349,7 +411,7 @@ func TestPoll(t *testing.T) {
 			},
 			PageLimit: 2,
 		}
-		rest := newRestyClient(&restConfig)
+		rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
 		httpmock.ActivateNonDefault(rest.GetClient())
 		defer httpmock.Reset()
 
@@ -375,12 +437,367 @@ func TestPoll(t *testing.T) {
 			consumer:     consumerMock,
 		}
 		err := receiver.Start(ctx, nil)
+		r.NoError(err)
+		<-reqStarted
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		r.NoError(receiver.Shutdown(shutdownCtx))
+		<-reqStoped
+	})
+
+	t.Run("when shutdown is called during pagination then the current page is delivered before stopping", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+		firstPageLastLogTimestamp := time.Now().Add(-7 * time.Second)
+
+		var delivered int
+		consumerMock := logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				delivered += logs.LogRecordCount()
+				return nil
+			},
+		}
+
+		restConfig := Config{
+			API: API{
+				Url: "https://api.cast.ai",
+				Key: uuid.NewString(),
+			},
+			PageLimit: 2,
+		}
+		rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
+		httpmock.ActivateNonDefault(rest.GetClient())
+		defer httpmock.Reset()
+
+		reqStarted := make(chan struct{})
+		releaseReq := make(chan struct{})
+		var requests int
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			`=~^https:\/\/api\.cast\.ai/v1/audit.?`,
+			func(req *http.Request) (*http.Response, error) {
+				requests++
+				close(reqStarted)
+				<-releaseReq
+				// There are more pages, yet they must not be requested once shutdown is started.
+				return httpmock.NewStringResponse(200, newResponseWithTwoItem(firstPageLastLogTimestamp, uuid.NewString())), nil
+			})
+
+		st := storage.NewInMemoryStorage(logger, time.Minute)
+		receiver := auditLogsReceiver{
+			logger:       logger,
+			pageLimit:    restConfig.PageLimit,
+			pollInterval: time.Millisecond,
+			wg:           &sync.WaitGroup{},
+			storage:      st,
+			rest:         rest,
+			consumer:     consumerMock,
+		}
+		r.NoError(receiver.Start(ctx, nil))
 		<-reqStarted
+
+		shutdownErr := make(chan error)
 		go func() {
-			err := receiver.Shutdown(ctx)
-			r.NoError(err)
+			shutdownErr <- receiver.Shutdown(ctx)
 		}()
-		<-reqStoped
+		r.Eventually(receiver.isDraining, time.Second, time.Millisecond)
+		close(releaseReq)
+		r.NoError(<-shutdownErr)
+
+		r.Equal(1, requests)
+		r.Equal(2, delivered)
+
+		// Window is not finished, so polling continues from the last delivered audit log after a restart.
+		pollData := st.Get()
+		r.NotNil(pollData.NextCheckPoint)
+		r.WithinDuration(firstPageLastLogTimestamp, *pollData.ToDate, 0)
+	})
+}
+
+func TestProcessAuditLogsWithHashChain(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	var auditLogsMap map[string]interface{}
+	r.NoError(json.Unmarshal([]byte(newResponseWithTwoItem(time.Now(), "")), &auditLogsMap))
+
+	checkPoint := time.Now().Add(-time.Hour)
+	st := storage.NewInMemoryStorage(zap.L(), 0)
+	r.NoError(st.Save(storage.PollData{CheckPoint: checkPoint}))
+
+	consumeErr := errors.New("consumer failed")
+	var exported plog.Logs
+	receiver := auditLogsReceiver{
+		logger:  zap.L(),
+		storage: st,
+		chain:   &hashChain{},
+		consumer: logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				exported = logs
+				return consumeErr
+			},
+		},
+	}
+
+	// Chain must not advance when logs were not consumed.
+	_, err := receiver.processAuditLogs(ctx, auditLogsMap)
+	r.ErrorIs(err, consumeErr)
+	r.Empty(receiver.chain.heads)
+	r.Nil(st.Get().Chain)
+
+	consumeErr = nil
+	_, err = receiver.processAuditLogs(ctx, auditLogsMap)
+	r.NoError(err)
+	r.Equal(uint64(2), receiver.chain.heads[""].Sequence)
+
+	// Head is saved right after audit logs are consumed, so its sequence numbers are not reused after a crash; the
+	// position is kept.
+	r.Equal(receiver.chain.heads[""], *st.Get().Chain)
+	r.WithinDuration(checkPoint, st.Get().CheckPoint, 0)
+
+	last := exported.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(1)
+	hash, ok := last.Attributes().Get(chain.HashAttribute)
+	r.True(ok)
+	r.Equal(receiver.chain.heads[""].Hash, hash.Str())
+	sequence, ok := last.Attributes().Get(chain.SequenceAttribute)
+	r.True(ok)
+	r.Equal(int64(2), sequence.Int())
+}
+
+func TestHashChainRestart(t *testing.T) {
+	t.Run("when routing is toggled between restarts then every chain continues from its persisted head", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+
+		filename := filepath.Join(t.TempDir(), "poll_data.json")
+		restart := func(routing RoutingConfig) *auditLogsReceiver {
+			st, err := storage.NewPersistentStorage(zap.L(), filename)
+			r.NoError(err)
+
+			cfg := newDefaultConfig().(*Config)
+			cfg.HashChain.Enabled = true
+			cfg.Routing = routing
+			receiver, err := newAuditLogsReceiver(zap.L(), cfg, st, logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					return nil
+				},
+			})
+			r.NoError(err)
+			return receiver
+		}
+		process := func(receiver *auditLogsReceiver, eventTypes ...string) {
+			var items []interface{}
+			for _, eventType := range eventTypes {
+				items = append(items, map[string]interface{}{
+					"id":        uuid.NewString(),
+					"eventType": eventType,
+					"time":      time.Now().UTC().Format(timestampLayout),
+				})
+			}
+			_, err := receiver.processAuditLogs(ctx, map[string]interface{}{"items": items})
+			r.NoError(err)
+			r.NoError(receiver.savePollData(receiver.storage.Get()))
+		}
+		routing := RoutingConfig{
+			Routes: []RouteConfig{{Name: "security", EventTypes: []string{"apiKey*"}}},
+		}
+
+		receiver := restart(RoutingConfig{})
+		process(receiver, "nodeAdded", "nodeAdded")
+		r.Equal(map[string]storage.ChainHead{"": receiver.chain.heads[""]}, restart(RoutingConfig{}).chain.heads)
+
+		// Head of the default route saved before routing was enabled does not replace heads saved afterwards.
+		receiver = restart(routing)
+		process(receiver, "nodeAdded", "apiKeyCreated", "nodeAdded")
+		heads := receiver.chain.heads
+		r.Equal(uint64(4), heads[""].Sequence)
+		r.Equal(uint64(1), heads["security"].Sequence)
+		r.Equal(heads, restart(routing).chain.heads)
+
+		// Heads of routes are kept while routing is disabled.
+		receiver = restart(RoutingConfig{})
+		r.Equal(heads, receiver.chain.heads)
+		process(receiver, "nodeAdded")
+		heads = receiver.chain.heads
+		r.Equal(uint64(5), heads[""].Sequence)
+		r.Equal(heads, restart(routing).chain.heads)
+	})
+}
+
+func TestStartCreatesAPIClient(t *testing.T) {
+	t.Run("when api client settings are configured then requests are sent using them", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+
+		apiKey := uuid.NewString()
+		requests := make(chan *http.Request, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case requests <- req:
+			default:
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Url = server.URL
+		cfg.API.Key = apiKey
+		cfg.API.Headers = map[string]configopaque.String{
+			"X-Custom-Header": "custom",
+		}
+		cfg.PollInterval = time.Hour
+		receiver, err := newAuditLogsReceiver(zap.L(), cfg, storage.NewInMemoryStorage(zap.L(), 0), logsConsumerMock{})
+		r.NoError(err)
+		receiver.telemetry = componenttest.NewNopTelemetrySettings()
+		receiver.buildInfo = component.BuildInfo{Command: "castai-collector", Version: "1.2.3"}
+
+		r.NoError(receiver.Start(ctx, componenttest.NewNopHost()))
+		req := <-requests
+		r.NoError(receiver.Shutdown(ctx))
+
+		r.Equal("/v1/audit", req.URL.Path)
+		r.Equal("castai/audit-logs-receiver/1.2.3 (castai-collector)", req.Header.Get("User-Agent"))
+		r.Equal(apiKey, req.Header.Get("X-API-Key"))
+		r.Equal("custom", req.Header.Get("X-Custom-Header"))
+		r.Equal(time.Minute, receiver.rest.GetClient().Timeout)
+	})
+
+	t.Run("when auth extension is configured then requests are authenticated by it", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+
+		requests := make(chan *http.Request, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case requests <- req:
+			default:
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		authID := component.MustNewID("bearertokenauth")
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Url = server.URL
+		cfg.API.Auth = &configauth.Config{AuthenticatorID: authID}
+		cfg.PollInterval = time.Hour
+
+		receiver, err := newAuditLogsReceiver(zap.L(), cfg, storage.NewInMemoryStorage(zap.L(), 0), logsConsumerMock{})
 		r.NoError(err)
+		receiver.telemetry = componenttest.NewNopTelemetrySettings()
+
+		host := hostWithExtensions{
+			extensions: map[component.ID]component.Component{
+				authID: headerAuthExtension{header: "Authorization", value: "Bearer token"},
+			},
+		}
+		r.NoError(receiver.Start(ctx, host))
+		req := <-requests
+		r.NoError(receiver.Shutdown(ctx))
+
+		r.Equal("Bearer token", req.Header.Get("Authorization"))
+		r.Empty(req.Header.Get("X-API-Key"))
+	})
+
+	t.Run("when auth extension is not available then receiver fails to start", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Auth = &configauth.Config{AuthenticatorID: component.MustNewID("bearertokenauth")}
+		receiver, err := newAuditLogsReceiver(zap.L(), cfg, storage.NewInMemoryStorage(zap.L(), 0), logsConsumerMock{})
+		r.NoError(err)
+
+		r.Error(receiver.Start(context.Background(), componenttest.NewNopHost()))
+	})
+
+	t.Run("when api client settings are invalid then receiver fails to start", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Key = uuid.NewString()
+		cfg.API.TLS.CAFile = filepath.Join(t.TempDir(), "missing-ca.pem")
+		receiver, err := newAuditLogsReceiver(zap.L(), cfg, storage.NewInMemoryStorage(zap.L(), 0), logsConsumerMock{})
+		r.NoError(err)
+
+		r.Error(receiver.Start(context.Background(), componenttest.NewNopHost()))
 	})
 }
+
+func TestNewRestyClient(t *testing.T) {
+	tests := []struct {
+		name string
+		api  API
+		want string
+	}{
+		{
+			name: "when url is set then audit logs endpoint is appended to it",
+			api:  API{Url: "https://api.cast.ai/"},
+			want: "https://api.cast.ai/v1/audit",
+		},
+		{
+			name: "when region is set then its url is used",
+			api:  API{Url: "https://api.cast.ai", Region: "eu"},
+			want: "https://api.eu.cast.ai/v1/audit",
+		},
+		{
+			name: "when path is set then it replaces the default one",
+			api:  API{Url: "https://audit-proxy.example.com", Path: "/castai/v2/audit"},
+			want: "https://audit-proxy.example.com/castai/v2/audit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, newRestyClient(tt.api, &http.Client{}, component.BuildInfo{}).BaseURL)
+		})
+	}
+}
+
+func TestProcessAuditLogsScope(t *testing.T) {
+	r := require.New(t)
+
+	var exported plog.Logs
+	receiver := auditLogsReceiver{
+		logger:    zap.L(),
+		buildInfo: component.BuildInfo{Command: "castai-collector", Version: "1.2.3"},
+		consumer: logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				exported = logs
+				return nil
+			},
+		},
+	}
+
+	var auditLogsMap map[string]interface{}
+	r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(time.Now())), &auditLogsMap))
+	_, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+	r.NoError(err)
+
+	scope := exported.ResourceLogs().At(0).ScopeLogs().At(0).Scope()
+	r.Equal(metadata.ScopeName, scope.Name())
+	r.Equal("1.2.3", scope.Version())
+}
+
+func BenchmarkProcessAuditLogs(b *testing.B) {
+	receiver := auditLogsReceiver{
+		logger: zap.NewNop(),
+		consumer: logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				return nil
+			},
+		},
+	}
+
+	var auditLogsMap map[string]interface{}
+	require.NoError(b, json.Unmarshal(newResponseWithItems(b, 1000, time.Now()), &auditLogsMap))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
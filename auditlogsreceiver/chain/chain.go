@@ -0,0 +1,213 @@
+// Package chain implements tamper-evident hash chain over exported audit logs. Every log record carries a sequence
+// number and SHA-256 hash of the previous record's hash combined with canonical JSON of its own timestamp, body and
+// attributes, so any altered, reordered or missing record breaks the chain.
+package chain
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+const (
+	HashAttribute     = "castai.audit.chain_hash"
+	SequenceAttribute = "castai.audit.chain_sequence"
+	// OrganizationAttribute is a resource attribute set in multi-organization mode; every organization has own chain.
+	OrganizationAttribute = "castai.organization"
+	// RouteAttribute is set on records when routing is configured; every route has own chain.
+	RouteAttribute = "castai.audit.route"
+
+	// maxLineSize limits size of a single line of exported file, file exporter writes one request per line.
+	maxLineSize = 64 * 1024 * 1024
+)
+
+// Link computes hash of the record, which follows a record with prevHash. Chain attributes themselves are not part of
+// the hash.
+func Link(prevHash string, record plog.LogRecord) (string, error) {
+	attributes := record.Attributes().AsRaw()
+	delete(attributes, HashAttribute)
+	delete(attributes, SequenceAttribute)
+
+	// Marshalling maps produces sorted keys, which makes JSON canonical.
+	data, err := json.Marshal(map[string]interface{}{
+		"timestamp":  record.Timestamp(),
+		"body":       record.Body().AsRaw(),
+		"attributes": attributes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshalling record: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Append links the record to the chain by setting its chain attributes and returns a new head of the chain.
+func Append(head storage.ChainHead, record plog.LogRecord) (storage.ChainHead, error) {
+	hash, err := Link(head.Hash, record)
+	if err != nil {
+		return head, err
+	}
+
+	next := storage.ChainHead{
+		Sequence: head.Sequence + 1,
+		Hash:     hash,
+	}
+	record.Attributes().PutStr(HashAttribute, next.Hash)
+	record.Attributes().PutInt(SequenceAttribute, int64(next.Sequence))
+
+	return next, nil
+}
+
+// Key identifies a chain; both organization and route are empty unless the receiver is configured with them.
+type Key struct {
+	Organization string
+	Route        string
+}
+
+func (k Key) String() string {
+	var parts []string
+	if k.Organization != "" {
+		parts = append(parts, fmt.Sprintf("organization %q", k.Organization))
+	}
+	if k.Route != "" {
+		parts = append(parts, fmt.Sprintf("route %q", k.Route))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Compare orders keys by organization and route.
+func (k Key) Compare(other Key) int {
+	return cmp.Or(strings.Compare(k.Organization, other.Organization), strings.Compare(k.Route, other.Route))
+}
+
+// Result summarizes verified part of a chain.
+type Result struct {
+	Records uint64
+	First   *storage.ChainHead
+	Last    *storage.ChainHead
+}
+
+// Verify validates chains in a file produced by file exporter using JSON format (one OTLP JSON logs request per
+// line). Every organization and route has its own chain, identified by OrganizationAttribute of the resource and
+// RouteAttribute of the record, so records of several chains may be interleaved in one file; results are returned per
+// chain.
+//
+// heads maps chain to the head preceding its first record in the file, and is updated with the last verified record
+// of every chain, so consecutive files can be verified by passing the same map. A chain missing in heads follows
+// anchor; when anchor is nil, its first record is trusted and the chain is verified from it onwards, which allows
+// verifying rotated files.
+func Verify(r io.Reader, anchor *storage.ChainHead, heads map[Key]storage.ChainHead) (map[Key]Result, error) {
+	results := map[Key]Result{}
+	if heads == nil {
+		heads = map[Key]storage.ChainHead{}
+	}
+
+	unmarshaler := &plog.JSONUnmarshaler{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		logs, err := unmarshaler.UnmarshalLogs(scanner.Bytes())
+		if err != nil {
+			return results, fmt.Errorf("line %d: parsing logs: %w", line, err)
+		}
+
+		for i := 0; i < logs.ResourceLogs().Len(); i++ {
+			resourceLogs := logs.ResourceLogs().At(i)
+			organization := ""
+			if value, ok := resourceLogs.Resource().Attributes().Get(OrganizationAttribute); ok {
+				organization = value.Str()
+			}
+
+			scopeLogs := resourceLogs.ScopeLogs()
+			for j := 0; j < scopeLogs.Len(); j++ {
+				records := scopeLogs.At(j).LogRecords()
+				for k := 0; k < records.Len(); k++ {
+					key := Key{Organization: organization}
+					if value, ok := records.At(k).Attributes().Get(RouteAttribute); ok {
+						key.Route = value.Str()
+					}
+
+					prev := anchor
+					if head, ok := heads[key]; ok {
+						prev = &head
+					}
+
+					head, err := verifyRecord(prev, records.At(k))
+					if err != nil {
+						if key != (Key{}) {
+							return results, fmt.Errorf("line %d: %s: %w", line, key, err)
+						}
+						return results, fmt.Errorf("line %d: %w", line, err)
+					}
+
+					result := results[key]
+					if result.First == nil {
+						result.First = &head
+					}
+					result.Last = &head
+					result.Records++
+					results[key] = result
+					heads[key] = head
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return results, fmt.Errorf("reading file: %w", err)
+	}
+
+	return results, nil
+}
+
+func verifyRecord(prev *storage.ChainHead, record plog.LogRecord) (storage.ChainHead, error) {
+	hash, ok := record.Attributes().Get(HashAttribute)
+	if !ok {
+		return storage.ChainHead{}, fmt.Errorf("record is missing %s attribute", HashAttribute)
+	}
+	sequence, ok := record.Attributes().Get(SequenceAttribute)
+	if !ok || sequence.Type() != pcommon.ValueTypeInt {
+		return storage.ChainHead{}, fmt.Errorf("record is missing %s attribute", SequenceAttribute)
+	}
+	head := storage.ChainHead{
+		Sequence: uint64(sequence.Int()),
+		Hash:     hash.Str(),
+	}
+
+	// Without a previous record, the first one is trusted.
+	if prev == nil {
+		return head, nil
+	}
+
+	if head.Sequence != prev.Sequence+1 {
+		return head, fmt.Errorf("expected sequence %d, got %d: records are missing or reordered", prev.Sequence+1, head.Sequence)
+	}
+
+	expected, err := Link(prev.Hash, record)
+	if err != nil {
+		return head, fmt.Errorf("sequence %d: %w", head.Sequence, err)
+	}
+	if expected != head.Hash {
+		return head, fmt.Errorf("sequence %d: hash mismatch, record was altered", head.Sequence)
+	}
+
+	return head, nil
+}
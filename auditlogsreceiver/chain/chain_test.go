@@ -0,0 +1,217 @@
+package chain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+// newExportedFile mimics file exporter by writing every logs batch as a separate JSON line.
+func newExportedFile(t *testing.T, batches ...plog.Logs) *bytes.Buffer {
+	t.Helper()
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	marshaler := &plog.JSONMarshaler{}
+	for _, logs := range batches {
+		data, err := marshaler.MarshalLogs(logs)
+		r.NoError(err)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return &buf
+}
+
+func newChainedLogs(t *testing.T, head storage.ChainHead, ids ...string) (plog.Logs, storage.ChainHead) {
+	t.Helper()
+	return newRoutedLogs(t, head, "", ids...)
+}
+
+// newRoutedLogs creates chained records with route attribute, which is not set when route is empty.
+func newRoutedLogs(t *testing.T, head storage.ChainHead, route string, ids ...string) (plog.Logs, storage.ChainHead) {
+	t.Helper()
+	r := require.New(t)
+
+	logs := plog.NewLogs()
+	for _, id := range ids {
+		record := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+		err := record.Attributes().FromRaw(map[string]interface{}{
+			"id":        id,
+			"eventType": "clusterDeleted",
+			"labels": map[string]interface{}{
+				"clusterId": "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f",
+			},
+			"count": 5.0,
+		})
+		r.NoError(err)
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+		record.Body().SetStr("cluster deleted")
+		if route != "" {
+			record.Attributes().PutStr(RouteAttribute, route)
+		}
+
+		head, err = Append(head, record)
+		r.NoError(err)
+	}
+
+	return logs, head
+}
+
+func setOrganization(logs plog.Logs, organization string) {
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		logs.ResourceLogs().At(i).Resource().Attributes().PutStr(OrganizationAttribute, organization)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	r := require.New(t)
+
+	logs, head := newChainedLogs(t, storage.ChainHead{}, "a", "b")
+	r.Equal(uint64(2), head.Sequence)
+
+	first := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	sequence, ok := first.Attributes().Get(SequenceAttribute)
+	r.True(ok)
+	r.Equal(int64(1), sequence.Int())
+
+	// Hash is deterministic and does not depend on chain attributes.
+	expected, err := Link("", first)
+	r.NoError(err)
+	hash, ok := first.Attributes().Get(HashAttribute)
+	r.True(ok)
+	r.Equal(expected, hash.Str())
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("when chain is intact then verification succeeds", func(t *testing.T) {
+		r := require.New(t)
+
+		first, head := newChainedLogs(t, storage.ChainHead{}, "a", "b")
+		second, head := newChainedLogs(t, head, "c")
+
+		results, err := Verify(newExportedFile(t, first, second), &storage.ChainHead{}, nil)
+		r.NoError(err)
+		result := results[Key{}]
+		r.Equal(uint64(3), result.Records)
+		r.Equal(uint64(1), result.First.Sequence)
+		r.Equal(head, *result.Last)
+	})
+
+	t.Run("when anchor is not provided then the first record is trusted", func(t *testing.T) {
+		r := require.New(t)
+
+		_, head := newChainedLogs(t, storage.ChainHead{}, "a", "b")
+		logs, _ := newChainedLogs(t, head, "c", "d")
+
+		results, err := Verify(newExportedFile(t, logs), nil, nil)
+		r.NoError(err)
+		r.Equal(uint64(2), results[Key{}].Records)
+		r.Equal(uint64(3), results[Key{}].First.Sequence)
+
+		// Anchor from a different point must not match.
+		_, err = Verify(newExportedFile(t, logs), &storage.ChainHead{}, nil)
+		r.Error(err)
+	})
+
+	t.Run("when record is altered then verification fails", func(t *testing.T) {
+		r := require.New(t)
+
+		logs, _ := newChainedLogs(t, storage.ChainHead{}, "a", "b", "c")
+		logs.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0).Attributes().PutStr("eventType", "nothingHappened")
+
+		_, err := Verify(newExportedFile(t, logs), &storage.ChainHead{}, nil)
+		r.ErrorContains(err, "sequence 2: hash mismatch")
+	})
+
+	t.Run("when chains of several organizations are interleaved then every chain is verified separately", func(t *testing.T) {
+		r := require.New(t)
+
+		prodFirst, prodHead := newChainedLogs(t, storage.ChainHead{}, "a", "b")
+		devFirst, devHead := newChainedLogs(t, storage.ChainHead{}, "c")
+		prodSecond, prodHead := newChainedLogs(t, prodHead, "d")
+		devSecond, devHead := newChainedLogs(t, devHead, "e", "f")
+		for _, logs := range []plog.Logs{prodFirst, prodSecond} {
+			setOrganization(logs, "prod")
+		}
+		for _, logs := range []plog.Logs{devFirst, devSecond} {
+			setOrganization(logs, "dev")
+		}
+
+		heads := map[Key]storage.ChainHead{}
+		results, err := Verify(newExportedFile(t, prodFirst, devFirst, prodSecond, devSecond), &storage.ChainHead{}, heads)
+		r.NoError(err)
+		r.Equal(uint64(3), results[Key{Organization: "prod"}].Records)
+		r.Equal(prodHead, *results[Key{Organization: "prod"}].Last)
+		r.Equal(uint64(3), results[Key{Organization: "dev"}].Records)
+		r.Equal(devHead, *results[Key{Organization: "dev"}].Last)
+		r.Equal(map[Key]storage.ChainHead{{Organization: "prod"}: prodHead, {Organization: "dev"}: devHead}, heads)
+
+		// Record of one organization does not continue chain of another one.
+		setOrganization(devSecond, "prod")
+		_, err = Verify(newExportedFile(t, prodFirst, devFirst, prodSecond, devSecond), &storage.ChainHead{}, nil)
+		r.ErrorContains(err, `organization "prod": expected sequence 4, got 2`)
+	})
+
+	t.Run("when chains of several routes are interleaved then every chain is verified separately", func(t *testing.T) {
+		r := require.New(t)
+
+		security, securityHead := newRoutedLogs(t, storage.ChainHead{}, "security", "a")
+		operations, operationsHead := newRoutedLogs(t, storage.ChainHead{}, "operations", "b", "c")
+
+		results, err := Verify(newExportedFile(t, operations, security), &storage.ChainHead{}, nil)
+		r.NoError(err)
+		r.Equal(securityHead, *results[Key{Route: "security"}].Last)
+		r.Equal(operationsHead, *results[Key{Route: "operations"}].Last)
+	})
+
+	t.Run("when timestamp of record is altered then verification fails", func(t *testing.T) {
+		r := require.New(t)
+
+		logs, _ := newChainedLogs(t, storage.ChainHead{}, "a", "b", "c")
+		record := logs.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0)
+		record.SetTimestamp(record.Timestamp() + 1)
+
+		_, err := Verify(newExportedFile(t, logs), &storage.ChainHead{}, nil)
+		r.ErrorContains(err, "sequence 2: hash mismatch")
+	})
+
+	t.Run("when body of record is altered then verification fails", func(t *testing.T) {
+		r := require.New(t)
+
+		logs, _ := newChainedLogs(t, storage.ChainHead{}, "a", "b", "c")
+		logs.ResourceLogs().At(1).ScopeLogs().At(0).LogRecords().At(0).Body().SetStr("nothing happened")
+
+		_, err := Verify(newExportedFile(t, logs), &storage.ChainHead{}, nil)
+		r.ErrorContains(err, "sequence 2: hash mismatch")
+	})
+
+	t.Run("when record is dropped then verification fails", func(t *testing.T) {
+		r := require.New(t)
+
+		logs, _ := newChainedLogs(t, storage.ChainHead{}, "a", "b", "c")
+		logs.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+			id, _ := rl.ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("id")
+			return id.Str() == "b"
+		})
+
+		_, err := Verify(newExportedFile(t, logs), &storage.ChainHead{}, nil)
+		r.ErrorContains(err, "expected sequence 2, got 3")
+	})
+
+	t.Run("when record is not chained then verification fails", func(t *testing.T) {
+		r := require.New(t)
+
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Attributes().PutStr("id", "a")
+
+		_, err := Verify(newExportedFile(t, logs), nil, nil)
+		r.Error(err)
+	})
+}
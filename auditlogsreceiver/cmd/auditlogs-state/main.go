@@ -0,0 +1,178 @@
+// Command auditlogs-state inspects and edits poll data file of the receiver's persistent storage.
+//
+// Usage:
+//
+//	auditlogs-state show     --file FILE [--signing-key-file KEY]
+//	auditlogs-state validate --file FILE [--signing-key-file KEY]
+//	auditlogs-state rewind   --file FILE [--signing-key-file KEY] --to 2024-01-02T15:04:05Z [--force]
+//	auditlogs-state reset    --file FILE [--signing-key-file KEY]
+//
+// Rewinding past the current check point skips audit logs, so it requires --force. A signed file can only be shown
+// without the signing key. The receiver must be stopped while the file is edited,
+// otherwise its changes are overwritten.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+const usage = "usage: auditlogs-state <show|validate|rewind|reset> --file FILE [--signing-key-file KEY] [--to TIME] [--force]"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer, now time.Time) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("auditlogs-state "+command, flag.ContinueOnError)
+	filename := fs.String("file", "", "path to poll data file of persistent storage")
+	signingKeyFile := fs.String("signing-key-file", "", "path to a file with poll data signing key, if signing is enabled")
+	to := fs.String("to", "", "RFC3339 timestamp to rewind check point to (rewind only)")
+	force := fs.Bool("force", false, "allow moving check point forward, which skips audit logs (rewind only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *filename == "" {
+		return errors.New("poll data file must be provided")
+	}
+
+	st, signed, err := openStorage(*filename, *signingKeyFile)
+	if err != nil {
+		return err
+	}
+	// Without the key, signature of a signed file can be neither verified nor kept valid after editing.
+	if signed && *signingKeyFile == "" && command != "show" {
+		return fmt.Errorf("poll data file is signed, %s requires --signing-key-file", command)
+	}
+
+	switch command {
+	case "show":
+	case "validate":
+		// Loading already validates poll data, so reaching this point means that it is valid.
+		fmt.Fprintln(out, "poll data is valid")
+	case "rewind":
+		if *to == "" {
+			return errors.New("rewind requires --to timestamp")
+		}
+		checkPoint, err := time.Parse(time.RFC3339, *to)
+		if err != nil {
+			return fmt.Errorf("parsing --to timestamp: %w", err)
+		}
+		if checkPoint.After(now) {
+			return errors.New("check point cannot be rewound to the future")
+		}
+		if checkPoint.After(st.Get().CheckPoint) && !*force {
+			return errors.New("moving check point forward skips audit logs, use --force to do it anyway")
+		}
+
+		err = save(st, checkPoint)
+		if err != nil {
+			return err
+		}
+	case "reset":
+		err = save(st, now)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+
+	printPollData(out, st.Get(), now)
+
+	return nil
+}
+
+// openStorage opens existing poll data file and reports whether it is signed.
+func openStorage(filename, signingKeyFile string) (storage.Storage, bool, error) {
+	// Persistent storage creates a file when it does not exist, which is not desired when inspecting existing state.
+	jsonBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("opening poll data file: %w", err)
+	}
+
+	var signature struct {
+		Signature string `json:"signature"`
+	}
+	err = json.Unmarshal(jsonBytes, &signature)
+	if err != nil {
+		return nil, false, fmt.Errorf("parsing poll data file: %w", err)
+	}
+
+	var opts []storage.PersistentStorageOption
+	if signingKeyFile != "" {
+		key, err := os.ReadFile(signingKeyFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("reading signing key file: %w", err)
+		}
+		opts = append(opts, storage.WithSigning(bytes.TrimSpace(key), storage.IntegrityPolicyRefuse))
+	}
+
+	st, err := storage.NewPersistentStorage(zap.NewNop(), filename, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return st, signature.Signature != "", nil
+}
+
+// save moves check point and abandons the window being fetched, so the receiver starts a new one from the check point.
+func save(st storage.Storage, checkPoint time.Time) error {
+	pollData := st.Get()
+	pollData.CheckPoint = checkPoint
+	pollData.NextCheckPoint = nil
+	pollData.ToDate = nil
+
+	err := pollData.Validate()
+	if err != nil {
+		return fmt.Errorf("validating poll data: %w", err)
+	}
+
+	err = st.Save(pollData)
+	if err != nil {
+		return fmt.Errorf("saving poll data: %w", err)
+	}
+
+	return nil
+}
+
+func printPollData(out io.Writer, pollData storage.PollData, now time.Time) {
+	formatTime := func(tm *time.Time) string {
+		if tm == nil {
+			return "-"
+		}
+		return tm.UTC().Format(time.RFC3339Nano)
+	}
+
+	fmt.Fprintf(out, "check_point:      %s\n", formatTime(&pollData.CheckPoint))
+	fmt.Fprintf(out, "next_check_point: %s\n", formatTime(pollData.NextCheckPoint))
+	fmt.Fprintf(out, "to_date:          %s\n", formatTime(pollData.ToDate))
+	if pollData.Chain != nil {
+		fmt.Fprintf(out, "chain:            sequence %d, hash %s\n", pollData.Chain.Sequence, pollData.Chain.Hash)
+	}
+	for _, route := range slices.Sorted(maps.Keys(pollData.Chains)) {
+		head := pollData.Chains[route]
+		fmt.Fprintf(out, "chain of %s: sequence %d, hash %s\n", route, head.Sequence, head.Hash)
+	}
+	fmt.Fprintf(out, "lag:              %s\n", now.Sub(pollData.CheckPoint).Truncate(time.Second))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+func writePollData(t *testing.T, p storage.PollData) string {
+	t.Helper()
+	r := require.New(t)
+
+	jsonBytes, err := json.Marshal(&p)
+	r.NoError(err)
+
+	filename := filepath.Join(t.TempDir(), "audit_logs_poll_data.json")
+	r.NoError(os.WriteFile(filename, jsonBytes, 0600))
+
+	return filename
+}
+
+func readPollData(t *testing.T, filename string) storage.PollData {
+	t.Helper()
+	r := require.New(t)
+
+	jsonBytes, err := os.ReadFile(filename)
+	r.NoError(err)
+
+	var p storage.PollData
+	r.NoError(json.Unmarshal(jsonBytes, &p))
+
+	return p
+}
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	inProgress := storage.PollData{
+		CheckPoint:     now.Add(-time.Hour),
+		NextCheckPoint: lo.ToPtr(now.Add(-time.Minute)),
+		ToDate:         lo.ToPtr(now.Add(-30 * time.Minute)),
+	}
+
+	t.Run("when show is called then poll data and lag are printed", func(t *testing.T) {
+		r := require.New(t)
+
+		var out bytes.Buffer
+		err := run([]string{"show", "--file", writePollData(t, inProgress)}, &out, now)
+		r.NoError(err)
+		r.Contains(out.String(), "check_point:      2024-05-01T11:00:00Z")
+		r.Contains(out.String(), "next_check_point: 2024-05-01T11:59:00Z")
+		r.Contains(out.String(), "to_date:          2024-05-01T11:30:00Z")
+		r.Contains(out.String(), "lag:              1h0m0s")
+	})
+
+	t.Run("when validate is called on invalid poll data then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		invalid := inProgress
+		invalid.ToDate = nil
+
+		err := run([]string{"validate", "--file", writePollData(t, invalid)}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "to_date must be provided")
+
+		var out bytes.Buffer
+		err = run([]string{"validate", "--file", writePollData(t, inProgress)}, &out, now)
+		r.NoError(err)
+		r.Contains(out.String(), "poll data is valid")
+	})
+
+	t.Run("when rewind is called then check point is moved and window is abandoned", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := writePollData(t, inProgress)
+		err := run([]string{"rewind", "--file", filename, "--to", "2024-04-01T00:00:00Z"}, &bytes.Buffer{}, now)
+		r.NoError(err)
+
+		p := readPollData(t, filename)
+		r.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), p.CheckPoint.UTC())
+		r.Nil(p.NextCheckPoint)
+		r.Nil(p.ToDate)
+
+		err = run([]string{"rewind", "--file", filename, "--to", "2025-01-01T00:00:00Z"}, &bytes.Buffer{}, now)
+		r.Error(err)
+		err = run([]string{"rewind", "--file", filename}, &bytes.Buffer{}, now)
+		r.Error(err)
+	})
+
+	t.Run("when rewind moves check point forward then it is refused unless forced", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := writePollData(t, inProgress)
+		err := run([]string{"rewind", "--file", filename, "--to", "2024-05-01T11:30:00Z"}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "use --force")
+		r.Equal(inProgress.CheckPoint, readPollData(t, filename).CheckPoint)
+
+		err = run([]string{"rewind", "--file", filename, "--to", "2024-05-01T11:30:00Z", "--force"}, &bytes.Buffer{}, now)
+		r.NoError(err)
+		r.Equal(time.Date(2024, 5, 1, 11, 30, 0, 0, time.UTC), readPollData(t, filename).CheckPoint.UTC())
+	})
+
+	t.Run("when reset is called then check point is set to now", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := writePollData(t, inProgress)
+		err := run([]string{"reset", "--file", filename}, &bytes.Buffer{}, now)
+		r.NoError(err)
+
+		p := readPollData(t, filename)
+		r.WithinDuration(now, p.CheckPoint, 0)
+		r.Nil(p.NextCheckPoint)
+		r.Nil(p.ToDate)
+	})
+
+	t.Run("when poll data file does not exist then it is not created", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := filepath.Join(t.TempDir(), "missing.json")
+		err := run([]string{"show", "--file", filename}, &bytes.Buffer{}, now)
+		r.Error(err)
+		r.NoFileExists(filename)
+	})
+
+	t.Run("when signed poll data is edited then signature is kept valid", func(t *testing.T) {
+		r := require.New(t)
+
+		keyFile := filepath.Join(t.TempDir(), "key")
+		r.NoError(os.WriteFile(keyFile, []byte("secret\n"), 0600))
+		filename := filepath.Join(t.TempDir(), "audit_logs_poll_data.json")
+		_, err := storage.NewPersistentStorage(zap.NewNop(), filename, storage.WithSigning([]byte("secret"), storage.IntegrityPolicyRefuse))
+		r.NoError(err)
+
+		err = run([]string{"reset", "--file", filename, "--signing-key-file", keyFile}, &bytes.Buffer{}, now)
+		r.NoError(err)
+
+		err = run([]string{"validate", "--file", filename}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "poll data file is signed")
+		err = run([]string{"validate", "--file", filename, "--signing-key-file", keyFile}, &bytes.Buffer{}, now)
+		r.NoError(err)
+	})
+
+	t.Run("when signed poll data is accessed without signing key then only show is allowed", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := filepath.Join(t.TempDir(), "audit_logs_poll_data.json")
+		_, err := storage.NewPersistentStorage(zap.NewNop(), filename, storage.WithSigning([]byte("secret"), storage.IntegrityPolicyRefuse))
+		r.NoError(err)
+		signed, err := os.ReadFile(filename)
+		r.NoError(err)
+
+		err = run([]string{"show", "--file", filename}, &bytes.Buffer{}, now)
+		r.NoError(err)
+		err = run([]string{"validate", "--file", filename}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "poll data file is signed")
+		err = run([]string{"rewind", "--file", filename, "--to", "2024-04-01T00:00:00Z"}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "poll data file is signed")
+		err = run([]string{"reset", "--file", filename}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "poll data file is signed")
+
+		// File is left signed and untouched.
+		content, err := os.ReadFile(filename)
+		r.NoError(err)
+		r.Equal(signed, content)
+	})
+
+	t.Run("when unknown command is called then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		err := run([]string{"edit", "--file", writePollData(t, inProgress)}, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "unknown command")
+	})
+}
@@ -0,0 +1,183 @@
+// Command castai-audit-export exports CAST AI audit logs of a fixed time range into a file, without building
+// a collector.
+//
+// Usage:
+//
+//	CASTAI_API_KEY=<api_access_key> castai-audit-export --from 2024-01-01T00:00:00Z --to 2024-02-01T00:00:00Z \
+//	  --format ndjson|csv|otlp-json --output audit_logs.ndjson [--region us|eu] [--url URL] [--path PATH]
+//
+// API URL is resolved the same way as by the receiver: --region picks a preset, --url sets a custom one.
+//
+// Progress is stored in a state file (--state, defaults to <output>.state.json) after every page, so an interrupted
+// export continues where it stopped when the command is run again with the same arguments. Records of the page
+// being written at the moment of interruption may be exported twice.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	auditlogsreceiver "github.com/castai/audit-logs-receiver/audit-logs"
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stderr, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, progress io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("castai-audit-export", flag.ContinueOnError)
+	apiURL := fs.String("url", os.Getenv("CASTAI_API_URL"), "CAST AI API URL, defaults to the one of --region (CASTAI_API_URL)")
+	apiRegion := fs.String("region", os.Getenv("CASTAI_API_REGION"), "CAST AI API region: us or eu (CASTAI_API_REGION)")
+	apiPath := fs.String("path", "", "path of audit logs endpoint, defaults to /v1/audit")
+	apiKey := fs.String("key", os.Getenv("CASTAI_API_KEY"), "CAST AI API access key (CASTAI_API_KEY)")
+	from := fs.String("from", "", "RFC3339 timestamp of the beginning of the range (required)")
+	to := fs.String("to", "", "RFC3339 timestamp of the end of the range (required)")
+	clusterID := fs.String("cluster-id", "", "export audit logs of a single cluster only")
+	pageLimit := fs.Int("page-limit", 1000, "max number of audit logs fetched in one page (10...1000)")
+	format := fs.String("format", formatNDJSON, "output format: ndjson, csv or otlp-json")
+	output := fs.String("output", "", "output file, audit logs are appended to it (required)")
+	stateFile := fs.String("state", "", "file storing export progress, defaults to <output>.state.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return errors.New("output file must be provided")
+	}
+	if *stateFile == "" {
+		*stateFile = *output + ".state.json"
+	}
+
+	// Both ends of the range are required, so the same command resumes the same export.
+	if *from == "" || *to == "" {
+		return errors.New("beginning and end of the range must be provided")
+	}
+	fromDate, err := time.Parse(time.RFC3339, *from)
+	if err != nil {
+		return fmt.Errorf("parsing --from timestamp: %w", err)
+	}
+	toDate, err := time.Parse(time.RFC3339, *to)
+	if err != nil {
+		return fmt.Errorf("parsing --to timestamp: %w", err)
+	}
+	if !fromDate.Before(toDate) {
+		return errors.New("beginning of the range must precede its end")
+	}
+	if toDate.After(now) {
+		return errors.New("end of the range cannot be in the future")
+	}
+
+	// Defaults of the receiver are used, so API URL, region and path are resolved and validated the same way.
+	cfg := auditlogsreceiver.NewFactory().CreateDefaultConfig().(*auditlogsreceiver.Config)
+	cfg.API.Key = *apiKey
+	cfg.API.Region = *apiRegion
+	if *apiURL != "" {
+		cfg.API.Url = *apiURL
+	}
+	if *apiPath != "" {
+		cfg.API.Path = *apiPath
+	}
+	cfg.PageLimit = *pageLimit
+	cfg.Storage = map[string]interface{}{
+		"type":     "persistent",
+		"filename": *stateFile,
+	}
+	if *clusterID != "" {
+		cfg.Filters.ClusterID = clusterID
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	st, done, err := openState(*stateFile, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+	if done {
+		fmt.Fprintf(progress, "export to %s is already complete\n", *output)
+		return nil
+	}
+
+	// Header is written only to a new file, as the output is appended when export is resumed.
+	out, err := os.OpenFile(*output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening output file: %w", err)
+	}
+	defer out.Close()
+	info, err := out.Stat()
+	if err != nil {
+		return fmt.Errorf("opening output file: %w", err)
+	}
+
+	writer, err := newLogsWriter(out, *format, info.Size() == 0, progress)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(progress, "exporting audit logs from %s to %s into %s\n", fromDate.UTC().Format(time.RFC3339), toDate.UTC().Format(time.RFC3339), *output)
+	err = auditlogsreceiver.PollOnce(ctx, zap.NewNop(), cfg, st, writer)
+	if err != nil {
+		return fmt.Errorf("exporting audit logs (run the same command again to resume): %w", err)
+	}
+
+	fmt.Fprintf(progress, "export complete: %d audit logs written to %s\n", writer.records, *output)
+
+	return nil
+}
+
+// openState opens export progress stored in the state file, or creates a new one for the range.
+func openState(filename string, from, to time.Time) (st storage.Storage, done bool, err error) {
+	_, statErr := os.Stat(filename)
+	isNew := errors.Is(statErr, os.ErrNotExist)
+
+	st, err = storage.NewPersistentStorage(zap.NewNop(), filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("opening state file: %w", err)
+	}
+
+	if isNew {
+		// Setting the window upfront makes the poll fetch exactly the requested range.
+		err = st.Save(storage.PollData{
+			CheckPoint:     from,
+			NextCheckPoint: &to,
+			ToDate:         &to,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("saving state file: %w", err)
+		}
+
+		return st, false, nil
+	}
+
+	pollData := st.Get()
+	if pollData.NextCheckPoint == nil {
+		// Window is cleared once the whole range was exported.
+		if pollData.CheckPoint.Equal(to) {
+			return st, true, nil
+		}
+
+		return nil, false, fmt.Errorf("state file %s belongs to a different export", filename)
+	}
+	if !pollData.NextCheckPoint.Equal(to) || !pollData.CheckPoint.Equal(from) {
+		return nil, false, fmt.Errorf("state file %s belongs to a different export", filename)
+	}
+
+	return st, false, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const testTimestampLayout = "2006-01-02T15:04:05.999999999Z"
+
+func newItem(id string, tm time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"eventType": "clusterDeleted",
+		"initiatedBy": map[string]interface{}{
+			"id":    "google-oauth2|100187903622338083673",
+			"name":  "John Doe",
+			"email": "john@example.com",
+		},
+		"time":   tm.UTC().Format(testTimestampLayout),
+		"event":  map[string]interface{}{"cluster": map[string]interface{}{"name": "cluster-1"}},
+		"labels": map[string]interface{}{"clusterId": "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"},
+	}
+}
+
+// newAuditLogsServer serves three audit logs newest first, two per page, limited by the requested range;
+// failPages makes the given number of second page requests fail.
+func newAuditLogsServer(t *testing.T, to time.Time, failPages int) (*httptest.Server, *int) {
+	t.Helper()
+
+	items := []map[string]interface{}{
+		newItem("3", to.Add(-time.Minute)),
+		newItem("2", to.Add(-2*time.Minute)),
+		newItem("1", to.Add(-3*time.Minute)),
+	}
+
+	requests := 0
+	var selected []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.URL.Path != "/v1/audit" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query := req.URL.Query()
+		page := map[string]interface{}{}
+		switch query.Get("page.cursor") {
+		case "":
+			fromDate, err := time.Parse(testTimestampLayout, query.Get("fromDate"))
+			require.NoError(t, err)
+			toDate, err := time.Parse(testTimestampLayout, query.Get("toDate"))
+			require.NoError(t, err)
+
+			selected = nil
+			for _, item := range items {
+				tm, _ := time.Parse(testTimestampLayout, item["time"].(string))
+				if !tm.Before(fromDate) && tm.Before(toDate) {
+					selected = append(selected, item)
+				}
+			}
+			if len(selected) > 2 {
+				page["items"] = selected[:2]
+				page["nextCursor"] = "second"
+			} else {
+				page["items"] = selected
+			}
+		case "second":
+			if failPages > 0 {
+				failPages--
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			page["items"] = selected[2:]
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(page))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	to := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := to.Add(time.Hour)
+
+	args := func(url, format, output string) []string {
+		return []string{
+			"--url", url,
+			"--key", "key",
+			"--from", "2024-05-01T00:00:00Z",
+			"--to", "2024-05-01T12:00:00Z",
+			"--format", format,
+			"--output", output,
+		}
+	}
+
+	t.Run("when range is exported as ndjson then every audit log is written as a separate line", func(t *testing.T) {
+		r := require.New(t)
+
+		server, _ := newAuditLogsServer(t, to, 0)
+		output := filepath.Join(t.TempDir(), "audit_logs.ndjson")
+
+		var progress bytes.Buffer
+		err := run(ctx, args(server.URL, formatNDJSON, output), &progress, now)
+		r.NoError(err)
+		r.Contains(progress.String(), "export complete: 3 audit logs")
+
+		f, err := os.Open(output)
+		r.NoError(err)
+		defer f.Close()
+
+		var ids []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var item map[string]interface{}
+			r.NoError(json.Unmarshal(scanner.Bytes(), &item))
+			ids = append(ids, item["id"].(string))
+			r.NotEmpty(item["time"])
+		}
+		r.Equal([]string{"3", "2", "1"}, ids)
+	})
+
+	t.Run("when export is interrupted then running it again resumes from the last page", func(t *testing.T) {
+		r := require.New(t)
+
+		server, requests := newAuditLogsServer(t, to, 1)
+		output := filepath.Join(t.TempDir(), "audit_logs.csv")
+
+		err := run(ctx, args(server.URL, formatCSV, output), &bytes.Buffer{}, now)
+		r.Error(err)
+
+		err = run(ctx, args(server.URL, formatCSV, output), &bytes.Buffer{}, now)
+		r.NoError(err)
+
+		f, err := os.Open(output)
+		r.NoError(err)
+		defer f.Close()
+		rows, err := csv.NewReader(f).ReadAll()
+		r.NoError(err)
+		r.Equal(csvHeader, rows[0])
+		r.Len(rows, 4)
+		r.Equal("john@example.com", rows[3][5])
+		r.Equal(`{"cluster":{"name":"cluster-1"}}`, rows[3][7])
+
+		// Completed export is not fetched again.
+		calls := *requests
+		var progress bytes.Buffer
+		err = run(ctx, args(server.URL, formatCSV, output), &progress, now)
+		r.NoError(err)
+		r.Contains(progress.String(), "already complete")
+		r.Equal(calls, *requests)
+	})
+
+	t.Run("when range is exported as otlp-json then every page is written as a logs request", func(t *testing.T) {
+		r := require.New(t)
+
+		server, _ := newAuditLogsServer(t, to, 0)
+		output := filepath.Join(t.TempDir(), "audit_logs.json")
+
+		err := run(ctx, args(server.URL, formatOTLPJSON, output), &bytes.Buffer{}, now)
+		r.NoError(err)
+
+		f, err := os.Open(output)
+		r.NoError(err)
+		defer f.Close()
+
+		counts := []int{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			logs, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(scanner.Bytes())
+			r.NoError(err)
+			counts = append(counts, logs.LogRecordCount())
+		}
+		r.Equal([]int{2, 1}, counts)
+	})
+
+	t.Run("when state file belongs to another range then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		server, _ := newAuditLogsServer(t, to, 1)
+		output := filepath.Join(t.TempDir(), "audit_logs.ndjson")
+
+		err := run(ctx, args(server.URL, formatNDJSON, output), &bytes.Buffer{}, now)
+		r.Error(err)
+
+		otherRange := args(server.URL, formatNDJSON, output)
+		otherRange[5] = "2024-04-01T00:00:00Z"
+		err = run(ctx, otherRange, &bytes.Buffer{}, now)
+		r.ErrorContains(err, "belongs to a different export")
+	})
+
+	t.Run("when api path is provided then audit logs are requested from it", func(t *testing.T) {
+		r := require.New(t)
+
+		paths := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case paths <- req.URL.Path:
+			default:
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		output := filepath.Join(t.TempDir(), "audit_logs.ndjson")
+		err := run(ctx, append(args(server.URL, formatNDJSON, output), "--path", "/custom/audit"), &bytes.Buffer{}, now)
+		r.NoError(err)
+		r.Equal("/custom/audit", <-paths)
+	})
+
+	t.Run("when arguments are invalid then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		output := filepath.Join(t.TempDir(), "audit_logs.ndjson")
+		for _, a := range [][]string{
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z"},
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--output", output},
+			{"--key", "key", "--from", "2024-05-02T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output},
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output, "--format", "xml"},
+			{"--key", "", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output},
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output, "--region", "mars"},
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output, "--region", "eu", "--url", "https://example.com"},
+			{"--key", "key", "--from", "2024-05-01T00:00:00Z", "--to", "2024-05-01T12:00:00Z", "--output", output, "--path", "v1/audit"},
+		} {
+			err := run(ctx, a, &bytes.Buffer{}, now)
+			r.Error(err, fmt.Sprint(a))
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	formatNDJSON   = "ndjson"
+	formatCSV      = "csv"
+	formatOTLPJSON = "otlp-json"
+)
+
+var csvHeader = []string{"time", "id", "eventType", "initiatedBy.id", "initiatedBy.name", "initiatedBy.email", "labels.clusterId", "event"}
+
+// logsWriter is a consumer.Logs that writes every consumed page of audit logs to the output in the given format.
+type logsWriter struct {
+	out      io.Writer
+	format   string
+	progress io.Writer
+	records  int
+}
+
+func newLogsWriter(out io.Writer, format string, writeHeader bool, progress io.Writer) (*logsWriter, error) {
+	w := &logsWriter{
+		out:      out,
+		format:   format,
+		progress: progress,
+	}
+
+	switch format {
+	case formatNDJSON, formatOTLPJSON:
+	case formatCSV:
+		if writeHeader {
+			cw := csv.NewWriter(out)
+			if err := cw.Write(csvHeader); err != nil {
+				return nil, err
+			}
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+
+	return w, nil
+}
+
+func (w *logsWriter) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+func (w *logsWriter) ConsumeLogs(_ context.Context, logs plog.Logs) error {
+	var err error
+	switch w.format {
+	case formatOTLPJSON:
+		err = w.writeOTLPJSON(logs)
+	case formatNDJSON:
+		err = w.forEachRecord(logs, w.writeNDJSON)
+	case formatCSV:
+		err = w.forEachRecord(logs, w.writeCSV)
+	}
+	if err != nil {
+		return fmt.Errorf("writing audit logs: %w", err)
+	}
+
+	w.records += logs.LogRecordCount()
+	fmt.Fprintf(w.progress, "exported %d audit logs\n", w.records)
+
+	return nil
+}
+
+func (w *logsWriter) writeOTLPJSON(logs plog.Logs) error {
+	// Same layout as file exporter uses: one logs request per line.
+	marshaler := &plog.JSONMarshaler{}
+	data, err := marshaler.MarshalLogs(logs)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.out.Write(append(data, '\n'))
+	return err
+}
+
+func (w *logsWriter) forEachRecord(logs plog.Logs, write func(record plog.LogRecord) error) error {
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		scopeLogs := logs.ResourceLogs().At(i).ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			records := scopeLogs.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				if err := write(records.At(k)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *logsWriter) writeNDJSON(record plog.LogRecord) error {
+	item := record.Attributes().AsRaw()
+	item["time"] = formatTimestamp(record.Timestamp())
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.out.Write(append(data, '\n'))
+	return err
+}
+
+func (w *logsWriter) writeCSV(record plog.LogRecord) error {
+	attributes := record.Attributes()
+	str := func(path ...string) string {
+		m := attributes
+		for i, key := range path {
+			v, ok := m.Get(key)
+			if !ok {
+				return ""
+			}
+			if i == len(path)-1 {
+				return v.AsString()
+			}
+			if v.Type() != pcommon.ValueTypeMap {
+				return ""
+			}
+			m = v.Map()
+		}
+		return ""
+	}
+
+	cw := csv.NewWriter(w.out)
+	err := cw.Write([]string{
+		formatTimestamp(record.Timestamp()),
+		str("id"),
+		str("eventType"),
+		str("initiatedBy", "id"),
+		str("initiatedBy", "name"),
+		str("initiatedBy", "email"),
+		str("labels", "clusterId"),
+		str("event"),
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func formatTimestamp(ts pcommon.Timestamp) string {
+	if ts == 0 {
+		return ""
+	}
+	return ts.AsTime().UTC().Format(time.RFC3339Nano)
+}
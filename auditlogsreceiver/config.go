@@ -3,77 +3,440 @@ package auditlogsreceiver
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
+	"github.com/samber/lo"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+const (
+	defaultAPIURL  = "https://api.cast.ai"
+	defaultAPIPath = "/v1/audit"
 )
 
+// organizationNamePattern restricts organization names, as they are used in poll data file names.
+var organizationNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// apiRegions maps region presets to base URLs of CAST AI API.
+var apiRegions = map[string]string{
+	"us": "https://api.cast.ai",
+	"eu": "https://api.eu.cast.ai",
+}
+
 type API struct {
 	Url string `mapstructure:"url"`
-	Key string `mapstructure:"key"`
+	// Region is a preset of Url, one of: us, eu; it cannot be combined with Url other than the default one.
+	Region string `mapstructure:"region"`
+	// Path is a path of audit logs endpoint appended to Url, defaults to /v1/audit.
+	Path string `mapstructure:"path"`
+	Key  string `mapstructure:"key"`
+	// MaxResponseSize limits size of a page of audit logs in bytes, defaults to 64 MiB.
+	MaxResponseSize int64 `mapstructure:"max_response_size"`
+	// ClientConfig provides timeout, proxy, TLS, compression, headers and auth settings of the API client; its endpoint
+	// is not used, Url is used instead.
+	confighttp.ClientConfig `mapstructure:",squash"`
 }
 
 // Config defines the configuration for the TCP stats receiver.
 type Config struct {
-	API             API                    `mapstructure:"api"`
-	PollIntervalSec int                    `mapstructure:"poll_interval_sec"`
-	PageLimit       int                    `mapstructure:"page_limit"`
+	API          API           `mapstructure:"api"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Deprecated: PollIntervalSec is replaced by PollInterval and cannot be used together with it.
+	PollIntervalSec int `mapstructure:"poll_interval_sec"`
+	PageLimit       int `mapstructure:"page_limit"`
+	// BatchPages is number of pages whose audit logs are consumed at once, defaults to 1; poll position is saved after
+	// every batch.
+	BatchPages      int                    `mapstructure:"batch_pages"`
 	Storage         map[string]interface{} `mapstructure:"storage"`
 	Filters         FilterConfig           `mapstructure:"filters"`
+	Sampling        []SamplingRule         `mapstructure:"sampling"`
+	Redaction       RedactionConfig        `mapstructure:"redaction"`
+	HashChain       HashChainConfig        `mapstructure:"hash_chain"`
+	AdaptivePolling AdaptivePollingConfig  `mapstructure:"adaptive_polling"`
+	LokiLabels      LokiLabelsConfig       `mapstructure:"loki_labels"`
+	Routing         RoutingConfig          `mapstructure:"routing"`
+	// OutputProfile shapes records for a destination, one of: splunk, datadog; records are not shaped when it is not set.
+	OutputProfile string               `mapstructure:"output_profile"`
+	Splunk        SplunkProfileConfig  `mapstructure:"splunk"`
+	Datadog       DatadogProfileConfig `mapstructure:"datadog"`
+	// Webhook enables receiving audit logs pushed by CAST AI in addition to polling; it is disabled when not configured.
+	Webhook *WebhookConfig `mapstructure:"webhook"`
+	// Organizations makes receiver poll audit logs of several organizations, each with its own API key; api key
+	// is not used then.
+	Organizations []OrganizationConfig `mapstructure:"organizations"`
+}
+
+type OrganizationConfig struct {
+	// Name identifies organization in castai.organization resource attribute and in poll data file name.
+	Name string `mapstructure:"name"`
+	Key  string `mapstructure:"key"`
+	// Filters override receiver's cluster id filter and expression rules for this organization when set.
+	Filters FilterConfig `mapstructure:"filters"`
 }
 
 type FilterConfig struct {
 	ClusterID *string `mapstructure:"cluster_id,omitempty"`
+	// Expression is a CEL condition evaluated against audit log item (available as item variable); items matching
+	// neither Expression nor any of Rules are not exported.
+	Expression string       `mapstructure:"expression"`
+	Rules      []FilterRule `mapstructure:"rules"`
+	// InitiatedBy keeps or drops audit log items depending on who initiated them.
+	InitiatedBy InitiatedByFilterConfig `mapstructure:"initiated_by"`
+	// Labels must all match labels of audit log item for it to be exported.
+	Labels []LabelFilter `mapstructure:"labels"`
+}
+
+// LabelFilter matches a label of audit log item; exactly one of Equals, In and Regex must be set.
+type LabelFilter struct {
+	Key    string   `mapstructure:"key"`
+	Equals string   `mapstructure:"equals"`
+	In     []string `mapstructure:"in"`
+	// Regex must match the whole label value.
+	Regex string `mapstructure:"regex"`
+}
+
+// FilterRule is a named CEL condition; matches of every rule are counted separately.
+type FilterRule struct {
+	Name       string `mapstructure:"name"`
+	Expression string `mapstructure:"expression"`
+}
+
+// InitiatedByFilterConfig defines initiators of audit log items to be exported. When Include is set, only items of
+// matching initiators are exported; items of initiators matching Exclude are never exported.
+type InitiatedByFilterConfig struct {
+	Include InitiatorMatcher `mapstructure:"include"`
+	Exclude InitiatorMatcher `mapstructure:"exclude"`
+}
+
+// InitiatorMatcher matches initiator of audit log item when any of its criteria matches.
+type InitiatorMatcher struct {
+	IDs []string `mapstructure:"ids"`
+	// EmailDomains match domain of initiator's email, case-insensitively.
+	EmailDomains []string `mapstructure:"email_domains"`
+	// Names are glob patterns (see path.Match) matching initiator's name.
+	Names []string `mapstructure:"names"`
+	// ActorTypes are user, api_key or system.
+	ActorTypes []string `mapstructure:"actor_types"`
+}
+
+// SamplingRule limits number of exported audit log items of given event types; "*" matches event types not listed by
+// other rules. When both OneIn and MaxPerMinute are set, items kept by OneIn are rate limited by MaxPerMinute.
+type SamplingRule struct {
+	EventTypes []string `mapstructure:"event_types"`
+	// OneIn keeps one of N items, chosen deterministically by item id.
+	OneIn int `mapstructure:"one_in"`
+	// MaxPerMinute keeps at most K items per minute of audit log time.
+	MaxPerMinute int `mapstructure:"max_per_minute"`
+}
+
+// RoutingConfig names route of every audit log item in castai.audit.route resource attribute, so routing connector
+// can send items to separate pipelines; the attribute is not set when no routes are configured.
+type RoutingConfig struct {
+	// Routes are matched in order, the first matching one is used.
+	Routes []RouteConfig `mapstructure:"routes"`
+	// Default is the route of items matching none of Routes.
+	Default string `mapstructure:"default"`
+}
+
+// RouteConfig matches audit log items of any of EventTypes whose labels match all of Labels.
+type RouteConfig struct {
+	Name string `mapstructure:"name"`
+	// EventTypes are glob patterns (see path.Match), for example apiKey*.
+	EventTypes []string      `mapstructure:"event_types"`
+	Labels     []LabelFilter `mapstructure:"labels"`
+}
+
+// RedactionConfig defines which values of audit log items are dropped, masked or hashed before being exported.
+type RedactionConfig struct {
+	// SaltFile is a path to a file containing secret used as a key for hashing values; required by hash action.
+	SaltFile string          `mapstructure:"salt_file"`
+	Rules    []RedactionRule `mapstructure:"rules"`
+}
+
+type RedactionRule struct {
+	// Path is a dot separated path to a value in audit log item, for example: initiatedBy.email or event.*.ipAddress.
+	Path string `mapstructure:"path"`
+	// Action is one of: drop, mask, hash.
+	Action string `mapstructure:"action"`
+}
+
+// HashChainConfig enables tamper-evident hash chain over exported audit logs.
+type HashChainConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// AdaptivePollingConfig makes poll interval vary between min and max depending on how many audit logs are returned.
+type AdaptivePollingConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MinInterval time.Duration `mapstructure:"min_interval"`
+	MaxInterval time.Duration `mapstructure:"max_interval"`
+}
+
+// LokiLabelsConfig makes receiver emit low-cardinality values as log attributes together with a hint for Loki
+// exporter to use them as labels.
+type LokiLabelsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Labels is an allow-list of emitted labels, some of: eventType, clusterId, actorType; all of them by default.
+	Labels []string `mapstructure:"labels"`
+}
+
+// SplunkProfileConfig defines Splunk HEC event metadata set by splunk output profile.
+type SplunkProfileConfig struct {
+	Source     string `mapstructure:"source"`
+	SourceType string `mapstructure:"sourcetype"`
+	// Index is optional; index configured in Splunk HEC exporter is used when it is not set.
+	Index string `mapstructure:"index"`
+}
+
+// DatadogProfileConfig defines source, service and statuses set by datadog output profile.
+type DatadogProfileConfig struct {
+	Source  string `mapstructure:"source"`
+	Service string `mapstructure:"service"`
+	// Statuses maps event types to Datadog statuses (emergency, alert, critical, error, warning, notice, info, debug);
+	// DefaultStatus is used for event types which are not mapped.
+	Statuses      map[string]string `mapstructure:"statuses"`
+	DefaultStatus string            `mapstructure:"default_status"`
+}
+
+type WebhookConfig struct {
+	confighttp.ServerConfig `mapstructure:",squash"`
+	// Path is URL path audit logs are accepted on, defaults to /audit-logs.
+	Path string `mapstructure:"path"`
+	// SecretFile is a path to a file containing secret shared with the sender, used to verify request signatures.
+	SecretFile string `mapstructure:"secret_file"`
 }
 
 type InMemoryStorageConfig struct {
+	// Lookback defines how far back from the start time audit logs are fetched.
+	Lookback time.Duration `mapstructure:"lookback"`
+	// Deprecated: BackFromNowSec is replaced by Lookback and cannot be used together with it.
 	BackFromNowSec int `mapstructure:"back_from_now_sec"`
 }
 
+func (c InMemoryStorageConfig) lookback() time.Duration {
+	if c.BackFromNowSec != 0 {
+		return time.Second * time.Duration(c.BackFromNowSec)
+	}
+	return c.Lookback
+}
+
 type PersistentStorageConfig struct {
 	Filename string `mapstructure:"filename"`
+	// SigningKeyFile is a path to a file containing secret used to sign poll data file with HMAC-SHA256; signing is
+	// disabled when it is not provided.
+	SigningKeyFile string `mapstructure:"signing_key_file"`
+	// OnIntegrityFailure defines what happens when poll data file signature cannot be verified: refuse (default) or reset.
+	OnIntegrityFailure string `mapstructure:"on_integrity_failure"`
 }
 
 func newDefaultConfig() component.Config {
+	clientConfig := confighttp.NewDefaultClientConfig()
+	clientConfig.Timeout = time.Minute
+
 	// Default parameters.
 	return &Config{
 		API: API{
-			Url: "https://api.cast.ai",
-			Key: "",
+			Url:             defaultAPIURL,
+			Path:            defaultAPIPath,
+			MaxResponseSize: defaultMaxResponseSize,
+			Key:             "",
+			ClientConfig:    clientConfig,
+		},
+		PollInterval: 10 * time.Second,
+		PageLimit:    100,
+		BatchPages:   1,
+		Splunk: SplunkProfileConfig{
+			Source:     "castai",
+			SourceType: "castai:audit",
 		},
-		PollIntervalSec: 10,
-		PageLimit:       100,
+		Datadog: DatadogProfileConfig{
+			Source:        "castai",
+			Service:       "castai-audit-logs",
+			DefaultStatus: "info",
+		},
+		Routing: RoutingConfig{
+			Default: "default",
+		},
+	}
+}
+
+func (a API) baseURL() string {
+	if a.Region != "" {
+		return apiRegions[a.Region]
+	}
+	return a.Url
+}
+
+func (a API) path() string {
+	if a.Path == "" {
+		return defaultAPIPath
+	}
+	return a.Path
+}
+
+func (c Config) pollInterval() time.Duration {
+	if c.PollIntervalSec != 0 {
+		return time.Second * time.Duration(c.PollIntervalSec)
+	}
+	return c.PollInterval
+}
+
+// Unmarshal rejects deprecated poll_interval_sec provided together with poll_interval, which cannot be detected once
+// the default of poll_interval is applied.
+func (c *Config) Unmarshal(conf *confmap.Conf) error {
+	if conf.IsSet("poll_interval_sec") && conf.IsSet("poll_interval") {
+		return errors.New("poll_interval_sec and poll_interval cannot be used together, remove deprecated poll_interval_sec")
 	}
+
+	return conf.Unmarshal(c)
+}
+
+// decodeStorageConfig decodes storage configuration, which is kept as a raw map since its fields depend on the type.
+func decodeStorageConfig(raw map[string]interface{}, storageConfig interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     storageConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(raw)
 }
 
 func (c Config) Validate() error {
-	if c.API.Url == "" {
+	if c.API.Region != "" {
+		regionURL, ok := apiRegions[c.API.Region]
+		if !ok {
+			return fmt.Errorf("unsupported api region %q, must be one of: %s", c.API.Region, strings.Join(slices.Sorted(maps.Keys(apiRegions)), ", "))
+		}
+		// Default url is ignored when region is set, so only a custom one conflicts with the region.
+		if c.API.Url != "" && c.API.Url != defaultAPIURL && c.API.Url != regionURL {
+			return errors.New("api url and region cannot be used together")
+		}
+	}
+
+	if c.API.baseURL() == "" {
 		return errors.New("api url must be specified")
 	}
 
-	_, err := url.ParseRequestURI(c.API.Url)
+	_, err := url.ParseRequestURI(c.API.baseURL())
 	if err != nil {
 		return errors.New("api url must be in the form of <scheme>://<hostname>:<port>")
 	}
 
-	if c.API.Key == "" {
-		return errors.New("api access key cannot be empty")
+	if c.API.Path != "" && !strings.HasPrefix(c.API.Path, "/") {
+		return errors.New("api path must start with /")
+	}
+
+	if c.API.Endpoint != "" {
+		return errors.New("api endpoint is not supported, use api url instead")
 	}
 
-	if c.PollIntervalSec <= 0 {
+	if len(c.Organizations) > 0 {
+		err = c.validateOrganizations()
+		if err != nil {
+			return err
+		}
+	} else if c.API.Key == "" && c.API.Auth == nil {
+		// Access key may be omitted when requests are authenticated by an auth extension.
+		return errors.New("api access key cannot be empty unless auth extension is configured")
+	}
+
+	if c.API.Timeout < 0 {
+		return errors.New("api timeout cannot be negative")
+	}
+
+	if c.PollIntervalSec < 0 {
 		return errors.New("poll interval must be positive number")
 	}
+	pollInterval := c.pollInterval()
+	if pollInterval < time.Second {
+		return errors.New("poll interval must be at least 1s")
+	}
+
+	if c.AdaptivePolling.Enabled {
+		if c.AdaptivePolling.MinInterval <= 0 {
+			return errors.New("adaptive polling min interval must be positive")
+		}
+		if c.AdaptivePolling.MinInterval > pollInterval || pollInterval > c.AdaptivePolling.MaxInterval {
+			return errors.New("poll interval must be within adaptive polling min...max interval")
+		}
+	}
 
 	if c.PageLimit < 10 || 1000 < c.PageLimit {
 		return errors.New("page limit must be within 10...1000 interval")
 	}
 
-	if c.Filters.ClusterID != nil && *c.Filters.ClusterID != "" {
-		_, err := uuid.Parse(*c.Filters.ClusterID)
+	if c.API.MaxResponseSize < 0 {
+		return errors.New("api max response size cannot be negative")
+	}
+
+	if c.BatchPages < 1 {
+		return errors.New("batch pages must be at least 1")
+	}
+
+	err = c.Filters.validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateSampling(c.Sampling)
+	if err != nil {
+		return err
+	}
+
+	err = c.Routing.validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Redaction.validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.LokiLabels.validate()
+	if err != nil {
+		return err
+	}
+
+	switch c.OutputProfile {
+	case "":
+	case outputProfileSplunk:
+		if c.Splunk.Source == "" || c.Splunk.SourceType == "" {
+			return errors.New("splunk source and sourcetype must be provided for splunk output profile")
+		}
+	case outputProfileDatadog:
+		err = c.Datadog.validate()
 		if err != nil {
-			return errors.New("cluster id must be a valid UUID")
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported output profile %q", c.OutputProfile)
+	}
+
+	if c.Webhook != nil {
+		if c.Webhook.Endpoint == "" {
+			return errors.New("webhook endpoint must be specified")
+		}
+		if c.Webhook.Path != "" && !strings.HasPrefix(c.Webhook.Path, "/") {
+			return errors.New("webhook path must start with /")
+		}
+		if c.Webhook.SecretFile == "" {
+			return errors.New("webhook secret file must be provided")
 		}
 	}
 
@@ -90,13 +453,25 @@ func (c Config) Validate() error {
 	switch storageType {
 	case "in-memory":
 		var storageConfig InMemoryStorageConfig
-		err = mapstructure.Decode(c.Storage, &storageConfig)
+		err = decodeStorageConfig(c.Storage, &storageConfig)
 		if err != nil {
 			return fmt.Errorf("decoding in-memory storage configuration: %w", err)
 		}
+
+		if storageConfig.BackFromNowSec < 0 || storageConfig.Lookback < 0 {
+			return errors.New("lookback of in-memory storage cannot be negative")
+		}
+		if storageConfig.BackFromNowSec != 0 && storageConfig.Lookback != 0 {
+			return errors.New("back_from_now_sec and lookback of in-memory storage cannot be used together, remove deprecated back_from_now_sec")
+		}
+
+		// Head of the chain is kept with poll data, so with in-memory storage a new chain would start after a restart.
+		if c.HashChain.Enabled {
+			return errors.New("hash chain requires persistent storage")
+		}
 	case "persistent":
 		var storageConfig PersistentStorageConfig
-		err = mapstructure.Decode(c.Storage, &storageConfig)
+		err = decodeStorageConfig(c.Storage, &storageConfig)
 		if err != nil {
 			return fmt.Errorf("decoding persistent storage configuration: %w", err)
 		}
@@ -104,9 +479,264 @@ func (c Config) Validate() error {
 		if storageConfig.Filename == "" {
 			return fmt.Errorf("file name must be provided in persistent storage configuration")
 		}
+
+		switch storage.IntegrityPolicy(storageConfig.OnIntegrityFailure) {
+		case "", storage.IntegrityPolicyRefuse, storage.IntegrityPolicyReset:
+		default:
+			return fmt.Errorf("unsupported integrity failure policy %q in persistent storage configuration", storageConfig.OnIntegrityFailure)
+		}
+
+		if storageConfig.OnIntegrityFailure != "" && storageConfig.SigningKeyFile == "" {
+			return errors.New("signing key file must be provided when integrity failure policy is set in persistent storage configuration")
+		}
 	default:
 		return errors.New("unsupported storage type provided")
 	}
 
 	return nil
 }
+
+func (c Config) validateOrganizations() error {
+	if c.API.Key != "" {
+		return errors.New("api key cannot be used together with organizations, provide key of every organization instead")
+	}
+	if c.Webhook != nil {
+		return errors.New("webhook cannot be used together with organizations")
+	}
+
+	names := map[string]struct{}{}
+	for _, org := range c.Organizations {
+		if !organizationNamePattern.MatchString(org.Name) {
+			return fmt.Errorf("organization name %q must consist of letters, digits, '-' or '_'", org.Name)
+		}
+		if _, ok := names[org.Name]; ok {
+			return fmt.Errorf("organization name %q is not unique", org.Name)
+		}
+		names[org.Name] = struct{}{}
+
+		if org.Key == "" {
+			return fmt.Errorf("api access key of organization %q cannot be empty", org.Name)
+		}
+
+		err := org.Filters.validate()
+		if err != nil {
+			return fmt.Errorf("filters of organization %q: %w", org.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c FilterConfig) validate() error {
+	if c.ClusterID != nil && *c.ClusterID != "" {
+		_, err := uuid.Parse(*c.ClusterID)
+		if err != nil {
+			return errors.New("cluster id must be a valid UUID")
+		}
+	}
+
+	err := c.InitiatedBy.validate()
+	if err != nil {
+		return err
+	}
+
+	for _, label := range c.Labels {
+		err = label.validate()
+		if err != nil {
+			return err
+		}
+		// Equality of clusterId label is filtered by the API, which refuses cluster ids other than UUIDs.
+		if label.Key == clusterIDLabel && label.Equals != "" {
+			if _, err = uuid.Parse(label.Equals); err != nil {
+				return errors.New("clusterId label filter must be a valid UUID")
+			}
+		}
+		if label.Key == clusterIDLabel && label.Equals != "" && c.ClusterID != nil && *c.ClusterID != "" && label.Equals != *c.ClusterID {
+			return errors.New("cluster id filter conflicts with clusterId label filter")
+		}
+	}
+
+	rules := c.expressionRules()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	env, err := newExpressionEnv()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]struct{}{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return errors.New("filter rule name cannot be empty")
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("filter rule name %q is not unique", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		_, err = compileExpression(env, rule.Expression)
+		if err != nil {
+			return fmt.Errorf("compiling filter rule %q: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c LabelFilter) validate() error {
+	if c.Key == "" {
+		return errors.New("label filter key cannot be empty")
+	}
+
+	operators := lo.Count([]bool{c.Equals != "", len(c.In) > 0, c.Regex != ""}, true)
+	if operators != 1 {
+		return fmt.Errorf("label filter of %q must set exactly one of equals, in and regex", c.Key)
+	}
+
+	if c.Regex != "" {
+		_, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return fmt.Errorf("label filter regex of %q is malformed: %w", c.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (c InitiatedByFilterConfig) validate() error {
+	for _, matcher := range []InitiatorMatcher{c.Include, c.Exclude} {
+		for _, name := range matcher.Names {
+			if _, err := path.Match(name, ""); err != nil {
+				return fmt.Errorf("initiator name pattern %q is malformed", name)
+			}
+		}
+		for _, domain := range matcher.EmailDomains {
+			if domain == "" || strings.Contains(domain, "@") {
+				return fmt.Errorf("initiator email domain %q is malformed", domain)
+			}
+		}
+		for _, actor := range matcher.ActorTypes {
+			if !slices.Contains(actorTypes, actor) {
+				return fmt.Errorf("unsupported initiator actor type %q, use one of: %s", actor, strings.Join(actorTypes, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateSampling(rules []SamplingRule) error {
+	eventTypes := map[string]struct{}{}
+	for _, rule := range rules {
+		if len(rule.EventTypes) == 0 {
+			return errors.New("sampling rule event types cannot be empty")
+		}
+		for _, eventType := range rule.EventTypes {
+			if _, ok := eventTypes[eventType]; ok {
+				return fmt.Errorf("event type %q is sampled by several rules", eventType)
+			}
+			eventTypes[eventType] = struct{}{}
+		}
+
+		if rule.OneIn < 0 || rule.MaxPerMinute < 0 {
+			return fmt.Errorf("sampling rule of %s cannot be negative", strings.Join(rule.EventTypes, ", "))
+		}
+		if rule.OneIn == 0 && rule.MaxPerMinute == 0 {
+			return fmt.Errorf("sampling rule of %s must set one_in or max_per_minute", strings.Join(rule.EventTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (c RoutingConfig) validate() error {
+	if len(c.Routes) == 0 {
+		return nil
+	}
+	if c.Default == "" {
+		return errors.New("default route cannot be empty")
+	}
+
+	names := map[string]struct{}{}
+	for _, route := range c.Routes {
+		if route.Name == "" {
+			return errors.New("route name cannot be empty")
+		}
+		if _, ok := names[route.Name]; ok {
+			return fmt.Errorf("route name %q is not unique", route.Name)
+		}
+		names[route.Name] = struct{}{}
+
+		if len(route.EventTypes) == 0 && len(route.Labels) == 0 {
+			return fmt.Errorf("route %q must match event types or labels", route.Name)
+		}
+		for _, eventType := range route.EventTypes {
+			if _, err := path.Match(eventType, ""); err != nil {
+				return fmt.Errorf("event type pattern %q of route %q is malformed", eventType, route.Name)
+			}
+		}
+		for _, label := range route.Labels {
+			err := label.validate()
+			if err != nil {
+				return fmt.Errorf("route %q: %w", route.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c RedactionConfig) validate() error {
+	for _, rule := range c.Rules {
+		if rule.Path == "" {
+			return errors.New("redaction rule path cannot be empty")
+		}
+		if lo.Contains(strings.Split(rule.Path, "."), "") {
+			return fmt.Errorf("redaction rule path %q is malformed", rule.Path)
+		}
+
+		switch rule.Action {
+		case redactionActionDrop, redactionActionMask:
+		case redactionActionHash:
+			if c.SaltFile == "" {
+				return fmt.Errorf("redaction salt file must be provided for hash action (path %q)", rule.Path)
+			}
+		default:
+			return fmt.Errorf("unsupported redaction action %q for path %q", rule.Action, rule.Path)
+		}
+	}
+
+	return nil
+}
+
+func (c LokiLabelsConfig) validate() error {
+	for _, label := range c.Labels {
+		if slices.Contains(lokiHighCardinalityKeys, label) {
+			return fmt.Errorf("loki label %q has high cardinality, use one of: %s", label, strings.Join(lokiLabelsAllowed, ", "))
+		}
+		if !slices.Contains(lokiLabelsAllowed, label) {
+			return fmt.Errorf("unsupported loki label %q, use one of: %s", label, strings.Join(lokiLabelsAllowed, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (c DatadogProfileConfig) validate() error {
+	if c.Source == "" || c.Service == "" {
+		return errors.New("datadog source and service must be provided for datadog output profile")
+	}
+
+	if _, ok := datadogSeverities[c.DefaultStatus]; !ok {
+		return fmt.Errorf("unsupported datadog default status %q", c.DefaultStatus)
+	}
+	for eventType, status := range c.Statuses {
+		if _, ok := datadogSeverities[status]; !ok {
+			return fmt.Errorf("unsupported datadog status %q for event type %q", status, eventType)
+		}
+	}
+
+	return nil
+}
@@ -1,16 +1,38 @@
 package auditlogsreceiver
 
 import (
-	"github.com/google/uuid"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configauth"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/confmap"
 )
 
 func TestConfigValidate(t *testing.T) {
 	type fields struct {
 		API             API
+		PollInterval    time.Duration
 		PollIntervalSec int
 		PageLimit       int
+		BatchPages      int
 		Storage         map[string]interface{}
+		Filters         FilterConfig
+		Sampling        []SamplingRule
+		Redaction       RedactionConfig
+		HashChain       HashChainConfig
+		Webhook         *WebhookConfig
+		AdaptivePolling AdaptivePollingConfig
+		Organizations   []OrganizationConfig
+		LokiLabels      LokiLabelsConfig
+		Routing         RoutingConfig
+		OutputProfile   string
+		Splunk          SplunkProfileConfig
+		Datadog         DatadogProfileConfig
 	}
 	tests := []struct {
 		name    string
@@ -26,6 +48,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type":              "in-memory",
 					"back_from_now_sec": 10,
@@ -42,6 +65,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type":     "persistent",
 					"filename": uuid.NewString() + ".json",
@@ -58,6 +82,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type": "persistent",
 				},
@@ -73,6 +98,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type": "persistent",
 				},
@@ -88,6 +114,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 0,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type": "persistent",
 				},
@@ -103,6 +130,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       1001,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type": "persistent",
 				},
@@ -118,24 +146,1633 @@ func TestConfigValidate(t *testing.T) {
 				},
 				PollIntervalSec: 10,
 				PageLimit:       100,
+				BatchPages:      1,
 				Storage: map[string]interface{}{
 					"type": "invalid type",
 				},
 			},
 			wantErr: true,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			c := Config{
-				API:             tt.fields.API,
-				PollIntervalSec: tt.fields.PollIntervalSec,
-				PageLimit:       tt.fields.PageLimit,
-				Storage:         tt.fields.Storage,
-			}
-			if err := c.Validate(); (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
+		{
+			name: "filter expression and rules correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Expression: `item.eventType == "clusterDeleted"`,
+					Rules: []FilterRule{
+						{Name: "external-users", Expression: `has(item.initiatedBy.email) && !item.initiatedBy.email.endsWith("@example.com")`},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "filter expression with invalid syntax",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Expression: `item.eventType ==`,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "filter expression not evaluating to bool",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Expression: `item.eventType + "-suffix"`,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "filter rules with duplicate names",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Rules: []FilterRule{
+						{Name: "deletions", Expression: `item.eventType == "clusterDeleted"`},
+						{Name: "deletions", Expression: `item.eventType == "nodeDeleted"`},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "filter rule without name",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Rules: []FilterRule{
+						{Expression: `item.eventType == "clusterDeleted"`},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "filter rule named as reserved expression rule",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Expression: `item.eventType == "clusterDeleted"`,
+					Rules: []FilterRule{
+						{Name: "expression", Expression: `item.eventType == "nodeDeleted"`},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "initiator filter correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					InitiatedBy: InitiatedByFilterConfig{
+						Include: InitiatorMatcher{EmailDomains: []string{"example.com"}, Names: []string{"ci-*"}},
+						Exclude: InitiatorMatcher{ActorTypes: []string{"system"}, IDs: []string{uuid.NewString()}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "initiator filter with unsupported actor type",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					InitiatedBy: InitiatedByFilterConfig{
+						Exclude: InitiatorMatcher{ActorTypes: []string{"robot"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "initiator filter with malformed name pattern",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					InitiatedBy: InitiatedByFilterConfig{
+						Include: InitiatorMatcher{Names: []string{"ci-["}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "initiator filter with email instead of domain",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					InitiatedBy: InitiatedByFilterConfig{
+						Include: InitiatorMatcher{EmailDomains: []string{"john@example.com"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label filters correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{
+						{Key: "clusterId", Equals: "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"},
+						{Key: "team", In: []string{"data", "platform"}},
+						{Key: "environment", Regex: "prod-.*"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "label filter without key",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{{Equals: "platform"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label filter with several operators",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{{Key: "team", Equals: "platform", Regex: "plat.*"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label filter without operator",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{{Key: "team"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label filter with malformed regex",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{{Key: "team", Regex: "plat("}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "label filter conflicting with cluster id",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					ClusterID: lo.ToPtr("1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"),
+					Labels:    []LabelFilter{{Key: "clusterId", Equals: "8c2ae5a1-3e6f-4c0d-9d7b-2f1e0a4b5c6d"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "clusterId label filter with invalid UUID",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Filters: FilterConfig{
+					Labels: []LabelFilter{{Key: "clusterId", Equals: "not-a-uuid"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sampling rules correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Sampling: []SamplingRule{
+					{EventTypes: []string{"nodeAdded", "nodeRemoved"}, OneIn: 10, MaxPerMinute: 100},
+					{EventTypes: []string{"*"}, MaxPerMinute: 1000},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "sampling rule without event types",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Sampling: []SamplingRule{
+					{OneIn: 10},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sampling rule without limits",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Sampling: []SamplingRule{
+					{EventTypes: []string{"nodeAdded"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sampling rule with negative one in",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Sampling: []SamplingRule{
+					{EventTypes: []string{"nodeAdded"}, OneIn: -1},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sampling rules sharing event type",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Sampling: []SamplingRule{
+					{EventTypes: []string{"nodeAdded"}, OneIn: 10},
+					{EventTypes: []string{"nodeAdded", "nodeRemoved"}, MaxPerMinute: 10},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "routing correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security", EventTypes: []string{"apiKey*", "userLoggedIn"}},
+						{Name: "operations", Labels: []LabelFilter{{Key: "team", In: []string{"platform"}}}},
+					},
+					Default: "default",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "routing without default route",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security", EventTypes: []string{"apiKey*"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "routes with duplicate names",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security", EventTypes: []string{"apiKey*"}},
+						{Name: "security", EventTypes: []string{"userLoggedIn"}},
+					},
+					Default: "default",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route without conditions",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security"},
+					},
+					Default: "default",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route with malformed event type pattern",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security", EventTypes: []string{"apiKey["}},
+					},
+					Default: "default",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route with malformed label filter",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Routing: RoutingConfig{
+					Routes: []RouteConfig{
+						{Name: "security", Labels: []LabelFilter{{Key: "team"}}},
+					},
+					Default: "default",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "batch pages correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   10,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero batch pages",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   0,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative batch pages",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   -1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "max response size correct data",
+			fields: fields{
+				API: API{
+					Url:             "https://api.cast.ai",
+					Key:             uuid.NewString(),
+					MaxResponseSize: 16 << 20,
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative max response size",
+			fields: fields{
+				API: API{
+					Url:             "https://api.cast.ai",
+					Key:             uuid.NewString(),
+					MaxResponseSize: -1,
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "redaction rules correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Redaction: RedactionConfig{
+					SaltFile: "/etc/salt",
+					Rules: []RedactionRule{
+						{Path: "initiatedBy.email", Action: "hash"},
+						{Path: "event.*.ipAddress", Action: "mask"},
+						{Path: "initiatedBy.name", Action: "drop"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "redaction hash rule without salt file",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Redaction: RedactionConfig{
+					Rules: []RedactionRule{
+						{Path: "initiatedBy.email", Action: "hash"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "redaction rule with unsupported action",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Redaction: RedactionConfig{
+					Rules: []RedactionRule{
+						{Path: "initiatedBy.email", Action: "encrypt"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "redaction rule with malformed path",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Redaction: RedactionConfig{
+					Rules: []RedactionRule{
+						{Path: "initiatedBy..email", Action: "drop"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "persistent storage with signing correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":                 "persistent",
+					"filename":             uuid.NewString() + ".json",
+					"signing_key_file":     "/etc/signing-key",
+					"on_integrity_failure": "reset",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "persistent storage with unsupported integrity failure policy",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":                 "persistent",
+					"filename":             uuid.NewString() + ".json",
+					"signing_key_file":     "/etc/signing-key",
+					"on_integrity_failure": "ignore",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "persistent storage with integrity failure policy but without signing key",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":                 "persistent",
+					"filename":             uuid.NewString() + ".json",
+					"on_integrity_failure": "refuse",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hash chain with persistent storage correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": uuid.NewString() + ".json",
+				},
+				HashChain: HashChainConfig{Enabled: true},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hash chain with in-memory storage",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				HashChain: HashChainConfig{Enabled: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "webhook correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Webhook: &WebhookConfig{
+					ServerConfig: confighttp.ServerConfig{
+						Endpoint: "0.0.0.0:8080",
+					},
+					Path:       "/castai/audit",
+					SecretFile: "/etc/webhook-secret",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "webhook without secret file",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Webhook: &WebhookConfig{
+					ServerConfig: confighttp.ServerConfig{
+						Endpoint: "0.0.0.0:8080",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "webhook with invalid path",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				Webhook: &WebhookConfig{
+					ServerConfig: confighttp.ServerConfig{
+						Endpoint: "0.0.0.0:8080",
+					},
+					Path:       "castai/audit",
+					SecretFile: "/etc/webhook-secret",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "adaptive polling correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				AdaptivePolling: AdaptivePollingConfig{
+					Enabled:     true,
+					MinInterval: time.Second,
+					MaxInterval: time.Minute,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "adaptive polling with poll interval outside of min...max interval",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 120,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				AdaptivePolling: AdaptivePollingConfig{
+					Enabled:     true,
+					MinInterval: time.Second,
+					MaxInterval: time.Minute,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "adaptive polling without min interval",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				AdaptivePolling: AdaptivePollingConfig{
+					Enabled:     true,
+					MaxInterval: time.Minute,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "api key omitted when auth extension is configured",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					ClientConfig: confighttp.ClientConfig{
+						Auth: &configauth.Config{AuthenticatorID: component.MustNewID("bearertokenauth")},
+					},
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "api region instead of url",
+			fields: fields{
+				API: API{
+					Region: "eu",
+					Key:    uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "api region with default url",
+			fields: fields{
+				API: API{
+					Url:    "https://api.cast.ai",
+					Region: "eu",
+					Key:    uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "api region with custom url",
+			fields: fields{
+				API: API{
+					Url:    "https://audit-proxy.example.com",
+					Region: "eu",
+					Key:    uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported api region",
+			fields: fields{
+				API: API{
+					Region: "ap",
+					Key:    uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "api path correct data",
+			fields: fields{
+				API: API{
+					Url:  "https://audit-proxy.example.com",
+					Path: "/castai/v1/audit",
+					Key:  uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "api path without leading slash",
+			fields: fields{
+				API: API{
+					Url:  "https://api.cast.ai",
+					Path: "v1/audit",
+					Key:  uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "api endpoint instead of url",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+					ClientConfig: confighttp.ClientConfig{
+						Endpoint: "https://api.cast.ai",
+					},
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative api timeout",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+					ClientConfig: confighttp.ClientConfig{
+						Timeout: -time.Second,
+					},
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organizations correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod", Key: uuid.NewString()},
+					{Name: "dev", Key: uuid.NewString(), Filters: FilterConfig{ClusterID: lo.ToPtr(uuid.NewString())}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "organizations with api key",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod", Key: uuid.NewString()},
+					{Name: "dev", Key: uuid.NewString(), Filters: FilterConfig{ClusterID: lo.ToPtr(uuid.NewString())}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organizations with webhook",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Webhook: &WebhookConfig{
+					ServerConfig: confighttp.ServerConfig{Endpoint: "localhost:8080"},
+					SecretFile:   "/etc/castai/webhook-secret",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod", Key: uuid.NewString()},
+					{Name: "dev", Key: uuid.NewString(), Filters: FilterConfig{ClusterID: lo.ToPtr(uuid.NewString())}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organizations with duplicate names",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod", Key: uuid.NewString()},
+					{Name: "prod", Key: uuid.NewString()},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organization with invalid name",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "../prod", Key: uuid.NewString()},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organization without key",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "organization with invalid cluster id",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type":     "persistent",
+					"filename": "./audit_logs_poll_data.json",
+				},
+				Organizations: []OrganizationConfig{
+					{Name: "prod", Key: uuid.NewString(), Filters: FilterConfig{ClusterID: lo.ToPtr("cluster")}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "loki labels correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				LokiLabels: LokiLabelsConfig{
+					Enabled: true,
+					Labels:  []string{"eventType", "actorType"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "loki labels with high cardinality key",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				LokiLabels: LokiLabelsConfig{
+					Enabled: true,
+					Labels:  []string{"eventType", "id"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported loki label",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				LokiLabels: LokiLabelsConfig{
+					Enabled: true,
+					Labels:  []string{"region"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "splunk output profile correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "splunk",
+				Splunk: SplunkProfileConfig{
+					Source:     "castai",
+					SourceType: "castai:audit",
+					Index:      "audit",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "splunk output profile without sourcetype",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "splunk",
+				Splunk: SplunkProfileConfig{
+					Source: "castai",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "datadog output profile correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "datadog",
+				Datadog: DatadogProfileConfig{
+					Source:        "castai",
+					Service:       "castai-audit-logs",
+					Statuses:      map[string]string{"clusterDeleted": "warning"},
+					DefaultStatus: "info",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "datadog output profile with unsupported status",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "datadog",
+				Datadog: DatadogProfileConfig{
+					Source:        "castai",
+					Service:       "castai-audit-logs",
+					Statuses:      map[string]string{"clusterDeleted": "severe"},
+					DefaultStatus: "info",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "datadog output profile without service",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "datadog",
+				Datadog: DatadogProfileConfig{
+					Source:        "castai",
+					DefaultStatus: "info",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported output profile",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollIntervalSec: 10,
+				PageLimit:       100,
+				BatchPages:      1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+				OutputProfile: "elastic",
+			},
+			wantErr: true,
+		},
+		{
+			name: "poll interval and lookback as durations correct data",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type":     "in-memory",
+					"lookback": "24h",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "deprecated lookback together with lookback",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type":              "in-memory",
+					"lookback":          "24h",
+					"back_from_now_sec": 10,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "poll interval below 1s",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 500 * time.Millisecond,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type": "in-memory",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative lookback",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type":     "in-memory",
+					"lookback": "-1h",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed lookback",
+			fields: fields{
+				API: API{
+					Url: "https://api.cast.ai",
+					Key: uuid.NewString(),
+				},
+				PollInterval: 30 * time.Second,
+				PageLimit:    100,
+				BatchPages:   1,
+				Storage: map[string]interface{}{
+					"type":     "in-memory",
+					"lookback": "one day",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{
+				API:             tt.fields.API,
+				PollInterval:    tt.fields.PollInterval,
+				PollIntervalSec: tt.fields.PollIntervalSec,
+				PageLimit:       tt.fields.PageLimit,
+				BatchPages:      tt.fields.BatchPages,
+				Storage:         tt.fields.Storage,
+				Filters:         tt.fields.Filters,
+				Sampling:        tt.fields.Sampling,
+				Redaction:       tt.fields.Redaction,
+				HashChain:       tt.fields.HashChain,
+				Webhook:         tt.fields.Webhook,
+				Organizations:   tt.fields.Organizations,
+				LokiLabels:      tt.fields.LokiLabels,
+				Routing:         tt.fields.Routing,
+				OutputProfile:   tt.fields.OutputProfile,
+				Splunk:          tt.fields.Splunk,
+				Datadog:         tt.fields.Datadog,
+				AdaptivePolling: tt.fields.AdaptivePolling,
+			}
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigUnmarshal(t *testing.T) {
+	t.Run("when durations are provided as strings then they are decoded", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		err := confmap.NewFromStringMap(map[string]any{
+			"poll_interval": "30s",
+			"storage": map[string]any{
+				"type":     "in-memory",
+				"lookback": "24h",
+			},
+		}).Unmarshal(cfg)
+		r.NoError(err)
+		r.Equal(30*time.Second, cfg.pollInterval())
+
+		var storageConfig InMemoryStorageConfig
+		r.NoError(decodeStorageConfig(cfg.Storage, &storageConfig))
+		r.Equal(24*time.Hour, storageConfig.lookback())
+	})
+
+	t.Run("when deprecated fields are provided then they are used", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		err := confmap.NewFromStringMap(map[string]any{
+			"api": map[string]any{
+				"key": uuid.NewString(),
+			},
+			"poll_interval_sec": 60,
+			"storage": map[string]any{
+				"type":              "in-memory",
+				"back_from_now_sec": 10,
+			},
+		}).Unmarshal(cfg)
+		r.NoError(err)
+		r.Equal(time.Minute, cfg.pollInterval())
+		r.NoError(cfg.Validate())
+
+		var storageConfig InMemoryStorageConfig
+		r.NoError(decodeStorageConfig(cfg.Storage, &storageConfig))
+		r.Equal(10*time.Second, storageConfig.lookback())
+	})
+
+	t.Run("when deprecated poll interval is provided together with its replacement then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		err := confmap.NewFromStringMap(map[string]any{
+			"poll_interval_sec": 60,
+			"poll_interval":     "30s",
+			"storage": map[string]any{
+				"type": "in-memory",
+			},
+		}).Unmarshal(cfg)
+		r.ErrorContains(err, "poll_interval_sec and poll_interval cannot be used together")
+	})
 }
@@ -0,0 +1,42 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+// PollOnce runs a single poll cycle outside of the collector, using the same API client and conversion as
+// the receiver. It is meant for one-shot exports of a fixed time range: when poll data in the storage has a window
+// set (ToDate and NextCheckPoint), audit logs between CheckPoint and ToDate are fetched, and as progress is saved
+// after every page, an interrupted export is resumed by calling PollOnce again with the same storage.
+func PollOnce(ctx context.Context, logger *zap.Logger, cfg *Config, st storage.Storage, consumer consumer.Logs) error {
+	a, err := newAuditLogsReceiver(logger, cfg, st, consumer)
+	if err != nil {
+		return err
+	}
+
+	err = a.startRestClient(ctx, nopHost{})
+	if err != nil {
+		return fmt.Errorf("creating audit logs api client: %w", err)
+	}
+
+	_, err = a.poll(ctx, func() {})
+	if err != nil {
+		return fmt.Errorf("polling audit logs: %w", err)
+	}
+
+	return nil
+}
+
+// nopHost is used when running outside of the collector, where no extensions (for example, auth) are available.
+type nopHost struct{}
+
+func (nopHost) GetExtensions() map[component.ID]component.Component {
+	return nil
+}
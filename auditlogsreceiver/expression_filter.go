@@ -0,0 +1,155 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+)
+
+const (
+	// expressionRuleName names the rule defined by filters.expression in match counters.
+	expressionRuleName = "expression"
+	// expressionItemVariable is a variable holding the audit log item in filter expressions.
+	expressionItemVariable = "item"
+
+	filterDroppedMetric      = "castai_audit_logs_filter_dropped"
+	filterDroppedDescription = "Number of audit logs dropped by filters."
+	// Values of filter attribute of dropped audit logs counter.
+	filterExpression  = "expression"
+	filterInitiatedBy = "initiated_by"
+	filterLabels      = "labels"
+)
+
+type expressionRule struct {
+	name    string
+	program cel.Program
+}
+
+// expressionFilter keeps audit log items matching any of CEL rules and counts matches of every rule.
+type expressionFilter struct {
+	rules []expressionRule
+
+	matches metric.Int64Counter
+	errors  metric.Int64Counter
+	dropped metric.Int64Counter
+}
+
+func newExpressionFilter(cfg FilterConfig) (*expressionFilter, error) {
+	rules := cfg.expressionRules()
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	env, err := newExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &expressionFilter{}
+	for _, rule := range rules {
+		program, err := compileExpression(env, rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("filter rule %q: %w", rule.Name, err)
+		}
+		f.rules = append(f.rules, expressionRule{
+			name:    rule.Name,
+			program: program,
+		})
+	}
+
+	// Counters are no-op until meter provider of the collector is set.
+	err = f.setMeterProvider(noop.NewMeterProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *expressionFilter) setMeterProvider(meterProvider metric.MeterProvider) error {
+	if f == nil {
+		return nil
+	}
+
+	meter := meterProvider.Meter(metadata.ScopeName)
+
+	var err error
+	f.matches, err = meter.Int64Counter("castai_audit_logs_filter_matches",
+		metric.WithDescription("Number of audit logs matched by filter rule."),
+		metric.WithUnit("{records}"))
+	if err != nil {
+		return err
+	}
+
+	f.errors, err = meter.Int64Counter("castai_audit_logs_filter_errors",
+		metric.WithDescription("Number of audit logs filter rule failed to evaluate on."),
+		metric.WithUnit("{records}"))
+	if err != nil {
+		return err
+	}
+
+	f.dropped, err = meter.Int64Counter(filterDroppedMetric,
+		metric.WithDescription(filterDroppedDescription),
+		metric.WithUnit("{records}"))
+	return err
+}
+
+// keep tells if the item matches any rule. Every rule is evaluated, so match counters are accurate for each of them;
+// a rule which fails to evaluate (for example, refers to a missing key) does not match, and its failure is counted
+// separately, so a misspelled key shows up. Matches, failures and drops are added to counts. It is safe to call on nil expressionFilter, which keeps every item.
+func (f *expressionFilter) keep(ctx context.Context, item map[string]interface{}, counts *batchCounts) bool {
+	if f == nil {
+		return true
+	}
+
+	matched := false
+	for _, rule := range f.rules {
+		out, _, err := rule.program.ContextEval(ctx, map[string]interface{}{expressionItemVariable: item})
+		if err != nil {
+			counts.add(f.errors, attribute.String("rule", rule.name))
+			continue
+		}
+
+		if match, ok := out.Value().(bool); ok && match {
+			matched = true
+			counts.add(f.matches, attribute.String("rule", rule.name))
+		}
+	}
+
+	if !matched {
+		counts.add(f.dropped, attribute.String("filter", filterExpression))
+	}
+
+	return matched
+}
+
+func newExpressionEnv() (*cel.Env, error) {
+	return cel.NewEnv(cel.Variable(expressionItemVariable, cel.MapType(cel.StringType, cel.DynType)))
+}
+
+func compileExpression(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", ast.OutputType())
+	}
+
+	return env.Program(ast)
+}
+
+// expressionRules returns rules of the filter, including the one defined by expression.
+func (c FilterConfig) expressionRules() []FilterRule {
+	var rules []FilterRule
+	if c.Expression != "" {
+		rules = append(rules, FilterRule{Name: expressionRuleName, Expression: c.Expression})
+	}
+	return append(rules, c.Rules...)
+}
@@ -0,0 +1,180 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+func TestExpressionFilter(t *testing.T) {
+	t.Run("when no expression is configured then filter is disabled and keeps every item", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{})
+		r.NoError(err)
+		r.Nil(filter)
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider()))
+		r.True(filter.keep(context.Background(), newTestItem(t), &batchCounts{}))
+	})
+
+	t.Run("when expression matches then item is kept, otherwise it is dropped", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{
+			Expression: `item.eventType == "apiKeyCreated" && !item.initiatedBy.email.endsWith("@castai.com")`,
+		})
+		r.NoError(err)
+
+		item := newTestItem(t)
+		r.True(filter.keep(context.Background(), item, &batchCounts{}))
+
+		item["eventType"] = "clusterDeleted"
+		r.False(filter.keep(context.Background(), item, &batchCounts{}))
+	})
+
+	t.Run("when expression refers to missing key then it does not match", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{
+			Expression: `item.labels.clusterId == "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"`,
+		})
+		r.NoError(err)
+		r.False(filter.keep(context.Background(), newTestItem(t), &batchCounts{}))
+	})
+
+	t.Run("when rule fails to evaluate then failure is counted separately from drops", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{
+			Rules: []FilterRule{
+				{Name: "typo", Expression: `item.eventtype == "apiKeyCreated"`},
+				{Name: "api-keys", Expression: `item.eventType.startsWith("apiKey")`},
+			},
+		})
+		r.NoError(err)
+
+		reader := sdkmetric.NewManualReader()
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		counts := &batchCounts{}
+		item := newTestItem(t)
+		r.True(filter.keep(context.Background(), item, counts))
+		item["eventType"] = "clusterDeleted"
+		r.False(filter.keep(context.Background(), item, counts))
+		counts.record(context.Background())
+
+		dataPoints := collectDataPoints(t, reader, "castai_audit_logs_filter_errors")
+		r.Len(dataPoints, 1)
+		r.Equal(int64(2), dataPoints[0].Value)
+		rule, ok := dataPoints[0].Attributes.Value(attribute.Key("rule"))
+		r.True(ok)
+		r.Equal("typo", rule.AsString())
+		r.Equal(map[string]int64{"api-keys": 1}, collectRuleMatches(t, reader))
+		r.Equal(int64(1), collectSum(t, reader, filterDroppedMetric))
+	})
+
+	t.Run("when any of rules matches then item is kept and matches of every rule are counted", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{
+			Expression: `item.eventType == "clusterDeleted"`,
+			Rules: []FilterRule{
+				{Name: "users", Expression: `has(item.initiatedBy.email)`},
+				{Name: "api-keys", Expression: `item.eventType.startsWith("apiKey")`},
+			},
+		})
+		r.NoError(err)
+
+		reader := sdkmetric.NewManualReader()
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		counts := &batchCounts{}
+		item := newTestItem(t)
+		r.True(filter.keep(context.Background(), item, counts))
+		item["eventType"] = "clusterDeleted"
+		r.True(filter.keep(context.Background(), item, counts))
+		item["initiatedBy"] = map[string]interface{}{}
+		item["eventType"] = "nodeAdded"
+		r.False(filter.keep(context.Background(), item, counts))
+		counts.record(context.Background())
+
+		r.Equal(map[string]int64{
+			"expression": 1,
+			"users":      2,
+			"api-keys":   1,
+		}, collectRuleMatches(t, reader))
+		r.Equal(int64(1), collectSum(t, reader, "castai_audit_logs_filter_dropped"))
+	})
+
+	t.Run("when audit logs are processed then filtered out items are not exported yet move poll position", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newExpressionFilter(FilterConfig{Expression: `item.eventType == "apiKeyCreated"`})
+		r.NoError(err)
+
+		consumed := false
+		receiver := auditLogsReceiver{
+			logger:     zap.L(),
+			itemFilter: filter,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					consumed = true
+					return nil
+				},
+			},
+		}
+
+		auditLogTime := time.Now().UTC().Truncate(time.Millisecond)
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(auditLogTime)), &auditLogsMap))
+		lastAuditLogTimestamp, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+		r.False(consumed)
+		r.NotNil(lastAuditLogTimestamp)
+		r.True(auditLogTime.Equal(*lastAuditLogTimestamp))
+	})
+}
+
+func collectRuleMatches(t *testing.T, reader sdkmetric.Reader) map[string]int64 {
+	t.Helper()
+
+	matches := map[string]int64{}
+	for _, dp := range collectDataPoints(t, reader, "castai_audit_logs_filter_matches") {
+		rule, _ := dp.Attributes.Value(attribute.Key("rule"))
+		matches[rule.AsString()] = dp.Value
+	}
+	return matches
+}
+
+func collectSum(t *testing.T, reader sdkmetric.Reader, name string) int64 {
+	t.Helper()
+
+	var sum int64
+	for _, dp := range collectDataPoints(t, reader, name) {
+		sum += dp.Value
+	}
+	return sum
+}
+
+func collectDataPoints(t *testing.T, reader sdkmetric.Reader, name string) []metricdata.DataPoint[int64] {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Sum[int64]).DataPoints
+			}
+		}
+	}
+	return nil
+}
@@ -1,15 +1,18 @@
 package auditlogsreceiver
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+	"maps"
+	"net/http"
+	"os"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/go-resty/resty/v2"
-	"github.com/mitchellh/mapstructure"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/receiver"
@@ -40,26 +43,148 @@ func NewAuditLogsReceiver(
 	// This is where logger may be adjusted if needed.
 	logger := settings.Logger
 
+	if cfg.PollIntervalSec != 0 {
+		logger.Warn("poll_interval_sec is deprecated and will be removed, use poll_interval instead (for example, 10s)")
+	}
+
+	if len(cfg.Organizations) > 0 {
+		return newMultiOrganizationReceiver(settings, cfg, consumer)
+	}
+
 	st, err := newStorage(settings.Logger, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("creating storage: %w", err)
 	}
 
+	a, err := newAuditLogsReceiver(logger, cfg, st, consumer)
+	if err != nil {
+		return nil, err
+	}
+	a.telemetry = settings.TelemetrySettings
+	a.buildInfo = settings.BuildInfo
+
+	err = a.setMeterProvider(settings.MeterProvider)
+	if err != nil {
+		return nil, fmt.Errorf("creating filter metrics: %w", err)
+	}
+
+	return a, nil
+}
+
+// chainHeads returns heads of chains persisted with poll data. Chain of the default route is kept in chain until routing
+// is enabled and in chains afterwards, so poll data saved before routing was toggled may hold both; as sequence of a
+// chain only grows, the head with the larger sequence is the latest one.
+func chainHeads(pollData storage.PollData) map[string]storage.ChainHead {
+	heads := maps.Clone(pollData.Chains)
+	if pollData.Chain == nil {
+		return heads
+	}
+	if heads == nil {
+		heads = map[string]storage.ChainHead{}
+	}
+	if head, ok := heads[""]; !ok || head.Sequence < pollData.Chain.Sequence {
+		heads[""] = *pollData.Chain
+	}
+	return heads
+}
+
+func newAuditLogsReceiver(logger *zap.Logger, cfg *Config, st storage.Storage, consumer consumer.Logs) (*auditLogsReceiver, error) {
+	redactor, err := newRedactor(cfg.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("creating redactor: %w", err)
+	}
+
+	itemFilter, err := newExpressionFilter(cfg.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("creating filter: %w", err)
+	}
+
+	initiators, err := newInitiatorFilter(cfg.Filters.InitiatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("creating initiator filter: %w", err)
+	}
+
+	labels, err := newLabelFilter(cfg.Filters.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("creating label filter: %w", err)
+	}
+
+	sampler, err := newSampler(cfg.Sampling)
+	if err != nil {
+		return nil, fmt.Errorf("creating sampler: %w", err)
+	}
+
+	router, err := newRouter(cfg.Routing)
+	if err != nil {
+		return nil, fmt.Errorf("creating router: %w", err)
+	}
+
+	var chain *hashChain
+	if cfg.HashChain.Enabled {
+		// Chains continue from heads persisted together with poll data.
+		chain = &hashChain{
+			heads: chainHeads(st.Get()),
+		}
+	}
+
+	var (
+		webhookSecret []byte
+		delivered     *deliveredIDs
+	)
+	if cfg.Webhook != nil {
+		webhookSecret, err = readWebhookSecret(cfg.Webhook.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// Audit logs pushed via webhook are polled as well, so polling fills gaps; duplicates are dropped.
+		delivered = newDeliveredIDs(deliveredIDsCapacity)
+	}
+
 	return &auditLogsReceiver{
-		logger:       logger,
-		pollInterval: time.Second * time.Duration(cfg.PollIntervalSec),
-		pageLimit:    cfg.PageLimit,
+		logger:          logger,
+		pollInterval:    cfg.pollInterval(),
+		scheduler:       newPollScheduler(cfg.pollInterval(), cfg.AdaptivePolling),
+		pageLimit:       cfg.PageLimit,
+		batchPages:      cfg.BatchPages,
+		maxResponseSize: cfg.API.MaxResponseSize,
 		filter: filters{
-			clusterID: cfg.Filters.ClusterID,
+			clusterID: cfg.Filters.apiClusterID(),
 		},
-		wg:          &sync.WaitGroup{},
-		stopPolling: func() {},
-		storage:     st,
-		rest:        newRestyClient(cfg),
-		consumer:    consumer,
+		itemFilter:    itemFilter,
+		initiators:    initiators,
+		labels:        labels,
+		sampler:       sampler,
+		router:        router,
+		redactor:      redactor,
+		lokiLabels:    newLokiLabels(cfg.LokiLabels),
+		profile:       newOutputProfile(cfg),
+		chain:         chain,
+		delivered:     delivered,
+		webhook:       cfg.Webhook,
+		webhookSecret: webhookSecret,
+		wg:            &sync.WaitGroup{},
+		stopPolling:   func() {},
+		api:           cfg.API,
+		storage:       st,
+		consumer:      consumer,
 	}, nil
 }
 
+// userAgent identifies the receiver and the collector it is built into, so requests can be traced to a version.
+func userAgent(info component.BuildInfo) string {
+	version := info.Version
+	if version == "" {
+		version = "unknown"
+	}
+
+	ua := "castai/audit-logs-receiver/" + version
+	if info.Command != "" {
+		ua += " (" + info.Command + ")"
+	}
+	return ua
+}
+
 func newStorage(logger *zap.Logger, cfg *Config) (storage.Storage, error) {
 	// Configuration validation is done in config.validate method, so it is safe to use configuration without validations here.
 	storageType := cfg.Storage["type"].(string)
@@ -68,32 +193,63 @@ func newStorage(logger *zap.Logger, cfg *Config) (storage.Storage, error) {
 	switch storageType {
 	case "in-memory":
 		var storageConfig InMemoryStorageConfig
-		err := mapstructure.Decode(cfg.Storage, &storageConfig)
+		err := decodeStorageConfig(cfg.Storage, &storageConfig)
 		if err != nil {
 			return nil, fmt.Errorf("decoding in-memory storage configuration: %w", err)
 		}
 
-		return storage.NewInMemoryStorage(logger, storageConfig.BackFromNowSec), nil
+		if storageConfig.BackFromNowSec != 0 {
+			logger.Warn("back_from_now_sec of in-memory storage is deprecated and will be removed, use lookback instead (for example, 24h)")
+		}
+
+		return storage.NewInMemoryStorage(logger, storageConfig.lookback()), nil
 	case "persistent":
 		var storageConfig PersistentStorageConfig
-		err := mapstructure.Decode(cfg.Storage, &storageConfig)
+		err := decodeStorageConfig(cfg.Storage, &storageConfig)
 		if err != nil {
 			return nil, fmt.Errorf("decoding persistent storage configuration: %w", err)
 		}
 
-		return storage.NewPersistentStorage(logger, storageConfig.Filename)
+		var opts []storage.PersistentStorageOption
+		if storageConfig.SigningKeyFile != "" {
+			key, err := os.ReadFile(storageConfig.SigningKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading poll data signing key file: %w", err)
+			}
+
+			// Trailing new lines are common in files created by hand or mounted from secrets, so they are not part of the key.
+			key = bytes.TrimSpace(key)
+			if len(key) == 0 {
+				return nil, errors.New("poll data signing key file is empty")
+			}
+
+			policy := storage.IntegrityPolicy(storageConfig.OnIntegrityFailure)
+			if policy == "" {
+				policy = storage.IntegrityPolicyRefuse
+			}
+			opts = append(opts, storage.WithSigning(key, policy))
+		}
+
+		return storage.NewPersistentStorage(logger, storageConfig.Filename, opts...)
 	default:
 		return nil, fmt.Errorf("invalid storage type provided for audit logs exporter: %v", storageType)
 	}
 }
 
-func newRestyClient(cfg *Config) *resty.Client {
-	return resty.New().
-		// TODO: look up version during build process
-		SetHeader("User-Agent", "castai/audit-logs-receiver/0.1.0").
+// newRestyClient creates API client on top of HTTP client built from confighttp.ClientConfig, which takes care of
+// timeout, proxy, TLS, compression, custom headers and auth extensions. User-Agent is set only here, from build info of
+// the collector.
+func newRestyClient(api API, httpClient *http.Client, info component.BuildInfo) *resty.Client {
+	client := resty.NewWithClient(httpClient).
+		SetHeader("User-Agent", userAgent(info)).
 		SetHeader("Content-Type", "application/json").
 		SetRetryCount(1).
-		SetTimeout(time.Minute).
-		SetBaseURL(strings.TrimSuffix(cfg.API.Url, "/")+"/v1/audit").
-		SetHeader("X-API-Key", cfg.API.Key)
+		SetBaseURL(strings.TrimSuffix(api.baseURL(), "/") + api.path())
+
+	// Access key is not set when requests are authenticated by an auth extension.
+	if api.Key != "" {
+		client.SetHeader("X-API-Key", api.Key)
+	}
+
+	return client
 }
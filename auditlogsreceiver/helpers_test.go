@@ -2,6 +2,8 @@ package auditlogsreceiver
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"testing"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/plog"
 
@@ -134,3 +137,73 @@ func defaultResponderWithAssertions(t *testing.T, data *storage.PollData, pageLi
 		return httpmock.NewStringResponse(200, body), nil
 	}
 }
+
+type hostWithExtensions struct {
+	extensions map[component.ID]component.Component
+}
+
+func (h hostWithExtensions) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+// headerAuthExtension is an auth extension setting a static header, similar to bearertokenauth.
+type headerAuthExtension struct {
+	header string
+	value  string
+}
+
+func (e headerAuthExtension) Start(context.Context, component.Host) error {
+	return nil
+}
+
+func (e headerAuthExtension) Shutdown(context.Context) error {
+	return nil
+}
+
+func (e headerAuthExtension) RoundTripper(base http.RoundTripper) (http.RoundTripper, error) {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Header.Set(e.header, e.value)
+		return base.RoundTrip(req)
+	}), nil
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newResponseWithItems returns a page of n audit logs, which are one second apart starting from lastLogTimestamp.
+func newResponseWithItems(tb testing.TB, n int, lastLogTimestamp time.Time) []byte {
+	tb.Helper()
+
+	var items []interface{}
+	for i := 0; i < n; i++ {
+		items = append(items, map[string]interface{}{
+			"id":        "824e7a47-b8e3-430e-8a7d-" + fmt.Sprintf("%012d", i),
+			"eventType": "clusterDeleted",
+			"initiatedBy": map[string]interface{}{
+				"id":    "google-oauth2|100187903622338083673",
+				"name":  "Andrej Kislovskij",
+				"email": "andrej@cast.ai",
+			},
+			"time": lastLogTimestamp.Add(-time.Duration(i) * time.Second).UTC().Format(timestampLayout),
+			"event": map[string]interface{}{
+				"cluster": map[string]interface{}{
+					"id":           "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f",
+					"name":         "andrej-cluster-07-13-1",
+					"providerType": "gke",
+					"region":       "europe-west1",
+				},
+			},
+			"labels": map[string]interface{}{
+				"clusterId": "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f",
+			},
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"items": items})
+	require.NoError(tb, err)
+	return body
+}
@@ -0,0 +1,95 @@
+package auditlogsreceiver
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+)
+
+// initiatorFilter keeps or drops audit log items depending on their initiatedBy, for example to silence automation.
+type initiatorFilter struct {
+	cfg InitiatedByFilterConfig
+
+	dropped metric.Int64Counter
+}
+
+func newInitiatorFilter(cfg InitiatedByFilterConfig) (*initiatorFilter, error) {
+	if cfg.Include.empty() && cfg.Exclude.empty() {
+		return nil, nil
+	}
+
+	f := &initiatorFilter{cfg: cfg}
+
+	// Counter is no-op until meter provider of the collector is set.
+	err := f.setMeterProvider(noop.NewMeterProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *initiatorFilter) setMeterProvider(meterProvider metric.MeterProvider) error {
+	if f == nil {
+		return nil
+	}
+
+	var err error
+	f.dropped, err = meterProvider.Meter(metadata.ScopeName).Int64Counter(filterDroppedMetric,
+		metric.WithDescription(filterDroppedDescription),
+		metric.WithUnit("{records}"))
+	return err
+}
+
+// keep tells if the item's initiator is included and not excluded, adding drops to counts. It is safe to call on nil
+// initiatorFilter, which keeps every item.
+func (f *initiatorFilter) keep(item map[string]interface{}, counts *batchCounts) bool {
+	if f == nil {
+		return true
+	}
+
+	keep := (f.cfg.Include.empty() || f.cfg.Include.matches(item)) && !f.cfg.Exclude.matches(item)
+	if !keep {
+		counts.add(f.dropped, attribute.String("filter", filterInitiatedBy))
+	}
+
+	return keep
+}
+
+func (m InitiatorMatcher) empty() bool {
+	return len(m.IDs) == 0 && len(m.EmailDomains) == 0 && len(m.Names) == 0 && len(m.ActorTypes) == 0
+}
+
+func (m InitiatorMatcher) matches(item map[string]interface{}) bool {
+	initiatedBy, _ := item["initiatedBy"].(map[string]interface{})
+
+	if id, _ := initiatedBy["id"].(string); id != "" && slices.Contains(m.IDs, id) {
+		return true
+	}
+
+	if email, _ := initiatedBy["email"].(string); email != "" {
+		if _, domain, ok := strings.Cut(email, "@"); ok {
+			for _, d := range m.EmailDomains {
+				if strings.EqualFold(domain, d) {
+					return true
+				}
+			}
+		}
+	}
+
+	if name, _ := initiatedBy["name"].(string); name != "" {
+		for _, pattern := range m.Names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+
+	return slices.Contains(m.ActorTypes, actorType(item))
+}
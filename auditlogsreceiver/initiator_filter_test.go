@@ -0,0 +1,97 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestInitiatorFilter(t *testing.T) {
+	user := map[string]interface{}{
+		"initiatedBy": map[string]interface{}{"id": "google-oauth2|100187903622338083673", "name": "John Doe", "email": "john@Example.com"},
+	}
+	apiKey := map[string]interface{}{
+		"initiatedBy": map[string]interface{}{"id": "3f8b1a6e-4d2c-4b8e-9a51-7c0d2e6f1b93", "name": "terraform-ci"},
+	}
+	system := map[string]interface{}{
+		"initiatedBy": map[string]interface{}{},
+	}
+
+	tests := []struct {
+		name string
+		cfg  InitiatedByFilterConfig
+		want []bool // Whether user, api key and system items are kept.
+	}{
+		{
+			name: "when system is excluded then automation is dropped",
+			cfg:  InitiatedByFilterConfig{Exclude: InitiatorMatcher{ActorTypes: []string{actorTypeSystem}}},
+			want: []bool{true, true, false},
+		},
+		{
+			name: "when email domain is included then only its users are kept",
+			cfg:  InitiatedByFilterConfig{Include: InitiatorMatcher{EmailDomains: []string{"example.com"}}},
+			want: []bool{true, false, false},
+		},
+		{
+			name: "when id is excluded then its items are dropped",
+			cfg:  InitiatedByFilterConfig{Exclude: InitiatorMatcher{IDs: []string{"3f8b1a6e-4d2c-4b8e-9a51-7c0d2e6f1b93"}}},
+			want: []bool{true, false, true},
+		},
+		{
+			name: "when name glob is included and actor type excluded then exclusion wins",
+			cfg: InitiatedByFilterConfig{
+				Include: InitiatorMatcher{Names: []string{"terraform-*", "John *"}},
+				Exclude: InitiatorMatcher{ActorTypes: []string{actorTypeAPIKey}},
+			},
+			want: []bool{true, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			filter, err := newInitiatorFilter(tt.cfg)
+			r.NoError(err)
+
+			r.Equal(tt.want, []bool{
+				filter.keep(user, &batchCounts{}),
+				filter.keep(apiKey, &batchCounts{}),
+				filter.keep(system, &batchCounts{}),
+			})
+		})
+	}
+
+	t.Run("when no initiators are configured then filter is disabled and keeps every item", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newInitiatorFilter(InitiatedByFilterConfig{})
+		r.NoError(err)
+		r.Nil(filter)
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider()))
+		r.True(filter.keep(system, &batchCounts{}))
+	})
+
+	t.Run("when items are dropped then they are counted", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newInitiatorFilter(InitiatedByFilterConfig{Exclude: InitiatorMatcher{ActorTypes: []string{actorTypeSystem}}})
+		r.NoError(err)
+
+		reader := sdkmetric.NewManualReader()
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		counts := &batchCounts{}
+		r.False(filter.keep(system, counts))
+		r.False(filter.keep(map[string]interface{}{}, counts))
+		r.True(filter.keep(user, counts))
+		counts.record(context.Background())
+
+		dataPoints := collectDataPoints(t, reader, filterDroppedMetric)
+		r.Len(dataPoints, 1)
+		r.Equal(int64(2), dataPoints[0].Value)
+		filterName, _ := dataPoints[0].Attributes.Value("filter")
+		r.Equal(filterInitiatedBy, filterName.AsString())
+	})
+}
@@ -0,0 +1,140 @@
+package auditlogsreceiver
+
+import (
+	"regexp"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+)
+
+// clusterIDLabel is the only label which Audit Logs API filters by (using clusterId query parameter).
+const clusterIDLabel = "clusterId"
+
+type labelMatcher struct {
+	key    string
+	equals string
+	in     []string
+	regex  *regexp.Regexp
+}
+
+func (m labelMatcher) matches(labels map[string]interface{}) bool {
+	value, ok := labels[m.key].(string)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case m.regex != nil:
+		return m.regex.MatchString(value)
+	case len(m.in) > 0:
+		return slices.Contains(m.in, value)
+	default:
+		return value == m.equals
+	}
+}
+
+func newLabelMatchers(cfg []LabelFilter) ([]labelMatcher, error) {
+	var matchers []labelMatcher
+	for _, label := range cfg {
+		matcher := labelMatcher{
+			key:    label.Key,
+			equals: label.Equals,
+			in:     label.In,
+		}
+		if label.Regex != "" {
+			regex, err := regexp.Compile("^(?:" + label.Regex + ")$")
+			if err != nil {
+				return nil, err
+			}
+			matcher.regex = regex
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// matchLabels tells if labels of the item match all matchers.
+func matchLabels(matchers []labelMatcher, item map[string]interface{}) bool {
+	labels, _ := item["labels"].(map[string]interface{})
+	for _, matcher := range matchers {
+		if !matcher.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelFilter keeps audit log items whose labels match all matchers. Matchers pushed down to the API are checked as
+// well, as audit logs delivered via webhook are not filtered by the API.
+type labelFilter struct {
+	matchers []labelMatcher
+
+	dropped metric.Int64Counter
+}
+
+func newLabelFilter(cfg []LabelFilter) (*labelFilter, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	matchers, err := newLabelMatchers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f := &labelFilter{matchers: matchers}
+
+	// Counter is no-op until meter provider of the collector is set.
+	err = f.setMeterProvider(noop.NewMeterProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *labelFilter) setMeterProvider(meterProvider metric.MeterProvider) error {
+	if f == nil {
+		return nil
+	}
+
+	var err error
+	f.dropped, err = meterProvider.Meter(metadata.ScopeName).Int64Counter(filterDroppedMetric,
+		metric.WithDescription(filterDroppedDescription),
+		metric.WithUnit("{records}"))
+	return err
+}
+
+// keep tells if labels of the item match all matchers, adding drops to counts. It is safe to call on nil labelFilter,
+// which keeps every item.
+func (f *labelFilter) keep(item map[string]interface{}, counts *batchCounts) bool {
+	if f == nil {
+		return true
+	}
+
+	if !matchLabels(f.matchers, item) {
+		counts.add(f.dropped, attribute.String("filter", filterLabels))
+		return false
+	}
+
+	return true
+}
+
+// apiClusterID returns cluster id to filter audit logs by in the API: either the configured one, or the one of
+// clusterId label filter using equality.
+func (c FilterConfig) apiClusterID() *string {
+	if c.ClusterID != nil && *c.ClusterID != "" {
+		return c.ClusterID
+	}
+
+	for _, label := range c.Labels {
+		if label.Key == clusterIDLabel && label.Equals != "" {
+			return &label.Equals
+		}
+	}
+
+	return c.ClusterID
+}
@@ -0,0 +1,118 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestLabelFilter(t *testing.T) {
+	item := map[string]interface{}{
+		"labels": map[string]interface{}{
+			"clusterId": "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f",
+			"team":      "platform",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		labels []LabelFilter
+		want   bool
+	}{
+		{
+			name:   "when label equals then item is kept",
+			labels: []LabelFilter{{Key: "team", Equals: "platform"}},
+			want:   true,
+		},
+		{
+			name:   "when label is one of values then item is kept",
+			labels: []LabelFilter{{Key: "team", In: []string{"data", "platform"}}},
+			want:   true,
+		},
+		{
+			name:   "when regex matches whole label then item is kept",
+			labels: []LabelFilter{{Key: "clusterId", Regex: "1e6e37e0-.*"}},
+			want:   true,
+		},
+		{
+			name:   "when regex matches part of label only then item is dropped",
+			labels: []LabelFilter{{Key: "team", Regex: "plat"}},
+			want:   false,
+		},
+		{
+			name:   "when label is missing then item is dropped",
+			labels: []LabelFilter{{Key: "environment", Equals: "prod"}},
+			want:   false,
+		},
+		{
+			name: "when one of filters does not match then item is dropped",
+			labels: []LabelFilter{
+				{Key: "team", Equals: "platform"},
+				{Key: "clusterId", In: []string{"8c2ae5a1-3e6f-4c0d-9d7b-2f1e0a4b5c6d"}},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			filter, err := newLabelFilter(tt.labels)
+			r.NoError(err)
+			r.Equal(tt.want, filter.keep(item, &batchCounts{}))
+		})
+	}
+
+	t.Run("when no labels are configured then filter is disabled and keeps every item", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newLabelFilter(nil)
+		r.NoError(err)
+		r.Nil(filter)
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider()))
+		r.True(filter.keep(map[string]interface{}{}, &batchCounts{}))
+	})
+
+	t.Run("when items are dropped then they are counted", func(t *testing.T) {
+		r := require.New(t)
+
+		filter, err := newLabelFilter([]LabelFilter{{Key: "team", Equals: "data"}})
+		r.NoError(err)
+
+		reader := sdkmetric.NewManualReader()
+		r.NoError(filter.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		counts := &batchCounts{}
+		r.False(filter.keep(item, counts))
+		counts.record(context.Background())
+
+		dataPoints := collectDataPoints(t, reader, filterDroppedMetric)
+		r.Len(dataPoints, 1)
+		r.Equal(int64(1), dataPoints[0].Value)
+		filterName, _ := dataPoints[0].Attributes.Value("filter")
+		r.Equal(filterLabels, filterName.AsString())
+	})
+}
+
+func TestAPIClusterID(t *testing.T) {
+	t.Run("when clusterId label filter uses equality then it is pushed down to the API", func(t *testing.T) {
+		cfg := FilterConfig{Labels: []LabelFilter{
+			{Key: "team", Equals: "platform"},
+			{Key: "clusterId", Equals: "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"},
+		}}
+		require.Equal(t, "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f", lo.FromPtr(cfg.apiClusterID()))
+	})
+
+	t.Run("when clusterId label filter uses regex then it is applied client-side only", func(t *testing.T) {
+		cfg := FilterConfig{Labels: []LabelFilter{{Key: "clusterId", Regex: "1e6e37e0-.*"}}}
+		require.Nil(t, cfg.apiClusterID())
+	})
+
+	t.Run("when cluster id is configured then it is used", func(t *testing.T) {
+		cfg := FilterConfig{ClusterID: lo.ToPtr("1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f")}
+		require.Equal(t, "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f", lo.FromPtr(cfg.apiClusterID()))
+	})
+}
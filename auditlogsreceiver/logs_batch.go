@@ -0,0 +1,117 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"maps"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/chain"
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+// logsBatch accumulates log records of audit logs of one or more pages until they are consumed.
+type logsBatch struct {
+	logs plog.Logs
+	// resources holds resource and records of every route; organization and output profile attributes of resources
+	// are the same for all records of the receiver, so the route is the only part of resource identity which varies.
+	resources map[string]resourceRecords
+	// ids are ids of audit logs of the batch, marked as delivered once they are consumed.
+	ids    []string
+	pages  int
+	counts batchCounts
+}
+
+// batchCounts holds what audit logs of the batch add to filter metrics and sampling limits. It is applied only once the
+// batch is consumed, so audit logs of a batch which is polled again are not counted twice.
+type batchCounts struct {
+	metrics []counterAddition
+	// sampled counts items kept by max per minute sampling rules.
+	sampled map[samplingBucket]int
+}
+
+type counterAddition struct {
+	counter   metric.Int64Counter
+	attribute attribute.KeyValue
+}
+
+// add counts an item in the counter.
+func (c *batchCounts) add(counter metric.Int64Counter, attr attribute.KeyValue) {
+	c.metrics = append(c.metrics, counterAddition{counter: counter, attribute: attr})
+}
+
+// record adds counted items to metrics, with attributes common to all of them.
+func (c *batchCounts) record(ctx context.Context, attributes ...attribute.KeyValue) {
+	common := metric.WithAttributes(attributes...)
+	for _, m := range c.metrics {
+		m.counter.Add(ctx, 1, common, metric.WithAttributes(m.attribute))
+	}
+}
+
+type resourceRecords struct {
+	resource pcommon.Resource
+	records  plog.LogRecordSlice
+}
+
+// addPage counts a page of the batch, starting the batch with its first page.
+func (b *logsBatch) addPage() {
+	if b.pages == 0 {
+		b.logs = plog.NewLogs()
+		b.resources = map[string]resourceRecords{}
+	}
+	b.pages++
+}
+
+func (b *logsBatch) reset() {
+	*b = logsBatch{}
+}
+
+// records returns resource and log records of the route, creating them with the first record of the route.
+func (b *logsBatch) records(a *auditLogsReceiver, route string) (pcommon.Resource, plog.LogRecordSlice) {
+	if rr, ok := b.resources[route]; ok {
+		return rr.resource, rr.records
+	}
+
+	resourceLog := b.logs.ResourceLogs().AppendEmpty()
+	if a.organization != "" {
+		resourceLog.Resource().Attributes().PutStr(organizationAttribute, a.organization)
+	}
+	if route != "" {
+		resourceLog.Resource().Attributes().PutStr(routeAttribute, route)
+	}
+	scopeLog := resourceLog.ScopeLogs().AppendEmpty()
+	scopeLog.Scope().SetName(metadata.ScopeName)
+	scopeLog.Scope().SetVersion(a.buildInfo.Version)
+
+	rr := resourceRecords{
+		resource: resourceLog.Resource(),
+		records:  scopeLog.LogRecords(),
+	}
+	b.resources[route] = rr
+	return rr.resource, rr.records
+}
+
+// link links records of the batch to the hash chain of their route, following heads, and returns new heads of chains.
+func (b *logsBatch) link(heads map[string]storage.ChainHead) (map[string]storage.ChainHead, error) {
+	next := maps.Clone(heads)
+	if next == nil {
+		next = map[string]storage.ChainHead{}
+	}
+
+	for route, rr := range b.resources {
+		head := next[route]
+		for i := 0; i < rr.records.Len(); i++ {
+			var err error
+			head, err = chain.Append(head, rr.records.At(i))
+			if err != nil {
+				return heads, err
+			}
+		}
+		next[route] = head
+	}
+	return next, nil
+}
@@ -0,0 +1,281 @@
+package auditlogsreceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/chain"
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+	mock_storage "github.com/castai/audit-logs-receiver/audit-logs/storage/mock"
+)
+
+func TestLogsBatch(t *testing.T) {
+	t.Run("when audit logs of a page are processed then records share resource and scope", func(t *testing.T) {
+		r := require.New(t)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger: zap.L(),
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal(newResponseWithItems(t, 100, time.Now()), &auditLogsMap))
+		_, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		r.Equal(1, exported.ResourceLogs().Len())
+		r.Equal(1, exported.ResourceLogs().At(0).ScopeLogs().Len())
+		r.Equal(100, exported.LogRecordCount())
+	})
+
+	t.Run("when audit logs have several routes then records are grouped and chained by route", func(t *testing.T) {
+		r := require.New(t)
+
+		router, err := newRouter(RoutingConfig{
+			Routes:  []RouteConfig{{Name: "security", EventTypes: []string{"apiKey*"}}},
+			Default: "operations",
+		})
+		r.NoError(err)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger:  zap.L(),
+			router:  router,
+			storage: storage.NewInMemoryStorage(zap.L(), 0),
+			chain:   &hashChain{},
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var items []interface{}
+		for _, eventType := range []string{"nodeAdded", "apiKeyCreated", "nodeRemoved", "apiKeyDeleted", "nodeAdded"} {
+			items = append(items, map[string]interface{}{
+				"id":        uuid.NewString(),
+				"eventType": eventType,
+				"time":      time.Now().UTC().Format(timestampLayout),
+			})
+		}
+		_, err = receiver.processAuditLogs(context.Background(), map[string]interface{}{"items": items})
+		r.NoError(err)
+
+		r.Equal(2, exported.ResourceLogs().Len())
+		for i, want := range []struct {
+			route     string
+			sequences []int64
+		}{
+			{route: "operations", sequences: []int64{1, 2, 3}},
+			{route: "security", sequences: []int64{1, 2}},
+		} {
+			resourceLogs := exported.ResourceLogs().At(i)
+			route, ok := resourceLogs.Resource().Attributes().Get(routeAttribute)
+			r.True(ok)
+			r.Equal(want.route, route.Str())
+
+			var sequences []int64
+			records := resourceLogs.ScopeLogs().At(0).LogRecords()
+			for j := 0; j < records.Len(); j++ {
+				// Route is stamped on records, so the chain of every route can be told apart in exported files.
+				route, ok := records.At(j).Attributes().Get(routeAttribute)
+				r.True(ok)
+				r.Equal(want.route, route.Str())
+
+				sequence, ok := records.At(j).Attributes().Get(chain.SequenceAttribute)
+				r.True(ok)
+				sequences = append(sequences, sequence.Int())
+			}
+			r.Equal(want.sequences, sequences)
+		}
+		r.Equal(uint64(3), receiver.chain.heads["operations"].Sequence)
+		r.Equal(uint64(2), receiver.chain.heads["security"].Sequence)
+
+		data, err := (&plog.JSONMarshaler{}).MarshalLogs(exported)
+		r.NoError(err)
+		results, err := chain.Verify(bytes.NewReader(data), &storage.ChainHead{}, nil)
+		r.NoError(err)
+		r.Equal(receiver.chain.heads["operations"], *results[chain.Key{Route: "operations"}].Last)
+		r.Equal(receiver.chain.heads["security"], *results[chain.Key{Route: "security"}].Last)
+
+		// Heads of all routes are persisted, so every chain continues after a restart.
+		r.Nil(receiver.storage.Get().Chain)
+		r.Equal(receiver.chain.heads, receiver.storage.Get().Chains)
+	})
+}
+
+func TestFlushFilterCounts(t *testing.T) {
+	t.Run("when batch is not consumed then filter metrics are recorded only once it is consumed", func(t *testing.T) {
+		r := require.New(t)
+
+		// Only the first of 10 items is kept.
+		itemFilter, err := newExpressionFilter(FilterConfig{Expression: `item.id.endsWith("000")`})
+		r.NoError(err)
+
+		consumeErr := errors.New("consumer failed")
+		receiver := auditLogsReceiver{
+			logger:     zap.L(),
+			itemFilter: itemFilter,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					return consumeErr
+				},
+			},
+		}
+		reader := sdkmetric.NewManualReader()
+		r.NoError(receiver.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal(newResponseWithItems(t, 10, time.Now()), &auditLogsMap))
+
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.ErrorIs(err, consumeErr)
+		r.Zero(collectSum(t, reader, filterDroppedMetric))
+
+		consumeErr = nil
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+		r.Equal(int64(9), collectSum(t, reader, filterDroppedMetric))
+	})
+
+	t.Run("when receiver polls an organization then filter metrics carry organization attribute", func(t *testing.T) {
+		r := require.New(t)
+
+		itemFilter, err := newExpressionFilter(FilterConfig{Expression: `item.id.endsWith("000")`})
+		r.NoError(err)
+
+		receiver := auditLogsReceiver{
+			logger:       zap.L(),
+			organization: "prod",
+			itemFilter:   itemFilter,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					return nil
+				},
+			},
+		}
+		reader := sdkmetric.NewManualReader()
+		r.NoError(receiver.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal(newResponseWithItems(t, 10, time.Now()), &auditLogsMap))
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		dataPoints := collectDataPoints(t, reader, filterDroppedMetric)
+		r.Len(dataPoints, 1)
+		r.Equal(int64(9), dataPoints[0].Value)
+		organization, ok := dataPoints[0].Attributes.Value(organizationAttribute)
+		r.True(ok)
+		r.Equal("prod", organization.AsString())
+		filter, ok := dataPoints[0].Attributes.Value("filter")
+		r.True(ok)
+		r.Equal("expression", filter.AsString())
+	})
+}
+
+func TestPollBatchPages(t *testing.T) {
+	newReceiver := func(t *testing.T, storageMock storage.Storage, consume func(plog.Logs) error) *auditLogsReceiver {
+		rest := newRestyClient(API{Url: "https://api.cast.ai", Key: uuid.NewString()}, &http.Client{}, component.BuildInfo{})
+		httpmock.ActivateNonDefault(rest.GetClient())
+		t.Cleanup(httpmock.Reset)
+
+		// Three pages, the last one has no cursor.
+		lastLogTimestamp := time.Now().Add(-time.Second)
+		pages := []string{
+			newResponseWithTwoItem(lastLogTimestamp, "page-2"),
+			newResponseWithTwoItem(lastLogTimestamp.Add(-time.Second), "page-3"),
+			newResponseWithOneItem(lastLogTimestamp.Add(-2 * time.Second)),
+		}
+		page := 0
+		httpmock.RegisterResponder(http.MethodGet, `=~^https:\/\/api\.cast\.ai/v1/audit.?`,
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(http.StatusOK, pages[page])
+				page++
+				return resp, nil
+			})
+
+		return &auditLogsReceiver{
+			logger:     zap.L(),
+			pageLimit:  10,
+			batchPages: 2,
+			storage:    storageMock,
+			rest:       rest,
+			consumer:   logsConsumerMock{ConsumeLogsFunc: consume},
+		}
+	}
+
+	t.Run("when batch pages is configured then pages are consumed in batches and position is saved after every batch", func(t *testing.T) {
+		r := require.New(t)
+
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		storageMock := mock_storage.NewMockStorage(mockCtrl)
+		storageMock.EXPECT().Get().Return(storage.PollData{CheckPoint: time.Now().Add(-time.Minute)})
+		var saved []storage.PollData
+		storageMock.EXPECT().Save(gomock.Any()).Do(func(dt storage.PollData) {
+			saved = append(saved, dt)
+		}).Times(4)
+
+		var consumed []int
+		receiver := newReceiver(t, storageMock, func(logs plog.Logs) error {
+			consumed = append(consumed, logs.LogRecordCount())
+			return nil
+		})
+
+		stats, err := receiver.poll(context.Background(), nil)
+		r.NoError(err)
+		r.Equal(5, stats.records)
+		r.Equal([]int{4, 1}, consumed)
+		// No page was full, yet a batch was consumed before the last page.
+		r.True(stats.limitReached)
+
+		// Initial position, position after each of two batches, and the next check point.
+		r.NotNil(saved[1].ToDate)
+		r.NotNil(saved[2].ToDate)
+		r.True(saved[2].ToDate.Before(*saved[1].ToDate))
+		r.Nil(saved[3].ToDate)
+	})
+
+	t.Run("when batch is not consumed then its position is not saved", func(t *testing.T) {
+		r := require.New(t)
+
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		storageMock := mock_storage.NewMockStorage(mockCtrl)
+		storageMock.EXPECT().Get().Return(storage.PollData{CheckPoint: time.Now().Add(-time.Minute)})
+		// Only the initial position is saved.
+		storageMock.EXPECT().Save(gomock.Any()).Times(1)
+
+		consumeErr := errors.New("consumer failed")
+		receiver := newReceiver(t, storageMock, func(logs plog.Logs) error {
+			return consumeErr
+		})
+
+		_, err := receiver.poll(context.Background(), nil)
+		r.ErrorIs(err, consumeErr)
+	})
+}
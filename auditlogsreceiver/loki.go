@@ -0,0 +1,91 @@
+package auditlogsreceiver
+
+import (
+	"strings"
+)
+
+const (
+	// lokiAttributeLabelsHint is an attribute listing attributes which Loki exporter turns into labels.
+	lokiAttributeLabelsHint = "loki.attribute.labels"
+
+	lokiLabelEventType = "eventType"
+	lokiLabelClusterID = "clusterId"
+	lokiLabelActorType = "actorType"
+
+	actorTypeUser   = "user"
+	actorTypeAPIKey = "api_key"
+	actorTypeSystem = "system"
+)
+
+// lokiLabelsAllowed are labels with a small, bounded set of values; they are emitted by default.
+var lokiLabelsAllowed = []string{lokiLabelEventType, lokiLabelClusterID, lokiLabelActorType}
+
+// lokiHighCardinalityKeys are keys of audit log items with (nearly) unique values, which must not become Loki labels
+// as every value creates a new stream.
+var lokiHighCardinalityKeys = []string{"id", "time", "initiatedBy", "initiatedBy.id", "initiatedBy.name", "initiatedBy.email", "event", "labels"}
+
+// lokiLabels adds low-cardinality label values to log attributes together with a hint for Loki exporter.
+type lokiLabels struct {
+	labels []string
+}
+
+func newLokiLabels(cfg LokiLabelsConfig) *lokiLabels {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	labels := cfg.Labels
+	if len(labels) == 0 {
+		labels = lokiLabelsAllowed
+	}
+
+	return &lokiLabels{
+		labels: labels,
+	}
+}
+
+// apply adds label values and the hint to attributes. It is safe to call on nil lokiLabels.
+func (l *lokiLabels) apply(attributes map[string]interface{}, item map[string]interface{}, actor string) {
+	if l == nil {
+		return
+	}
+
+	var hint []string
+	for _, label := range l.labels {
+		var value string
+		switch label {
+		case lokiLabelEventType:
+			value, _ = item["eventType"].(string)
+		case lokiLabelClusterID:
+			labels, _ := item["labels"].(map[string]interface{})
+			value, _ = labels["clusterId"].(string)
+		case lokiLabelActorType:
+			value = actor
+		}
+
+		// Labels without value are not hinted, so Loki does not get empty labels.
+		if value == "" {
+			continue
+		}
+		attributes[label] = value
+		hint = append(hint, label)
+	}
+
+	if len(hint) > 0 {
+		attributes[lokiAttributeLabelsHint] = strings.Join(hint, ", ")
+	}
+}
+
+var actorTypes = []string{actorTypeUser, actorTypeAPIKey, actorTypeSystem}
+
+// actorType tells who initiated the audit log: a user (has email), an API key (has id only) or the system itself.
+func actorType(item map[string]interface{}) string {
+	initiatedBy, _ := item["initiatedBy"].(map[string]interface{})
+	if email, _ := initiatedBy["email"].(string); email != "" {
+		return actorTypeUser
+	}
+	if id, _ := initiatedBy["id"].(string); id != "" {
+		return actorTypeAPIKey
+	}
+	return actorTypeSystem
+}
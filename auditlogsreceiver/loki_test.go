@@ -0,0 +1,129 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestActorType(t *testing.T) {
+	tests := []struct {
+		name        string
+		initiatedBy interface{}
+		want        string
+	}{
+		{
+			name:        "when initiator has email then it is a user",
+			initiatedBy: map[string]interface{}{"id": "google-oauth2|100187903622338083673", "email": "john@example.com"},
+			want:        actorTypeUser,
+		},
+		{
+			name:        "when initiator has id only then it is an api key",
+			initiatedBy: map[string]interface{}{"id": "3f8b1a6e-4d2c-4b8e-9a51-7c0d2e6f1b93"},
+			want:        actorTypeAPIKey,
+		},
+		{
+			name:        "when initiator is empty then it is the system",
+			initiatedBy: map[string]interface{}{},
+			want:        actorTypeSystem,
+		},
+		{
+			name:        "when initiator is missing then it is the system",
+			initiatedBy: nil,
+			want:        actorTypeSystem,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, actorType(map[string]interface{}{"initiatedBy": tt.initiatedBy}))
+		})
+	}
+}
+
+func TestLokiLabels(t *testing.T) {
+	t.Run("when loki labels are disabled then attributes are left untouched", func(t *testing.T) {
+		r := require.New(t)
+
+		labels := newLokiLabels(LokiLabelsConfig{Labels: []string{lokiLabelEventType}})
+		r.Nil(labels)
+
+		attributes := map[string]interface{}{}
+		labels.apply(attributes, newTestItem(t), actorTypeUser)
+		r.Empty(attributes)
+	})
+
+	t.Run("when loki labels are enabled without allow-list then all labels with values are emitted", func(t *testing.T) {
+		r := require.New(t)
+
+		item := newTestItem(t)
+		item["labels"] = map[string]interface{}{"clusterId": "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f"}
+
+		attributes := map[string]interface{}{}
+		newLokiLabels(LokiLabelsConfig{Enabled: true}).apply(attributes, item, actorTypeAPIKey)
+		r.Equal(map[string]interface{}{
+			lokiLabelEventType:      "apiKeyCreated",
+			lokiLabelClusterID:      "1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f",
+			lokiLabelActorType:      actorTypeAPIKey,
+			lokiAttributeLabelsHint: "eventType, clusterId, actorType",
+		}, attributes)
+	})
+
+	t.Run("when allow-list is configured then only listed labels with values are emitted", func(t *testing.T) {
+		r := require.New(t)
+
+		attributes := map[string]interface{}{}
+		newLokiLabels(LokiLabelsConfig{
+			Enabled: true,
+			Labels:  []string{lokiLabelClusterID, lokiLabelActorType},
+		}).apply(attributes, newTestItem(t), actorTypeSystem)
+		r.Equal(map[string]interface{}{
+			lokiLabelActorType:      actorTypeSystem,
+			lokiAttributeLabelsHint: "actorType",
+		}, attributes)
+	})
+
+	t.Run("when audit logs are processed then actor type is derived before redaction", func(t *testing.T) {
+		r := require.New(t)
+
+		redactor, err := newRedactor(RedactionConfig{
+			Rules: []RedactionRule{
+				{Path: "initiatedBy.email", Action: redactionActionDrop},
+			},
+		})
+		r.NoError(err)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger:     zap.L(),
+			redactor:   redactor,
+			lokiLabels: newLokiLabels(LokiLabelsConfig{Enabled: true}),
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(time.Now())), &auditLogsMap))
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		attributes := exported.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes()
+		hint, ok := attributes.Get(lokiAttributeLabelsHint)
+		r.True(ok)
+		r.Equal("eventType, clusterId, actorType", hint.Str())
+		actor, ok := attributes.Get(lokiLabelActorType)
+		r.True(ok)
+		r.Equal(actorTypeUser, actor.Str())
+		clusterID, ok := attributes.Get(lokiLabelClusterID)
+		r.True(ok)
+		r.Equal("1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f", clusterID.Str())
+	})
+}
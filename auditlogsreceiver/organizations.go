@@ -0,0 +1,128 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/chain"
+)
+
+// organizationAttribute is a resource attribute set on audit logs polled for one of configured organizations.
+const organizationAttribute = chain.OrganizationAttribute
+
+// multiOrganizationReceiver polls audit logs of several organizations concurrently; every organization is polled
+// by its own receiver with its own API key, filters and poll data.
+type multiOrganizationReceiver struct {
+	receivers []*auditLogsReceiver
+}
+
+func newMultiOrganizationReceiver(settings receiver.Settings, cfg *Config, consumer consumer.Logs) (*multiOrganizationReceiver, error) {
+	m := &multiOrganizationReceiver{}
+
+	for _, org := range cfg.Organizations {
+		logger := settings.Logger.With(zap.String("organization", org.Name))
+		orgCfg := organizationConfig(cfg, org)
+
+		st, err := newStorage(logger, orgCfg)
+		if err != nil {
+			return nil, fmt.Errorf("creating storage of organization %q: %w", org.Name, err)
+		}
+
+		a, err := newAuditLogsReceiver(logger, orgCfg, st, consumer)
+		if err != nil {
+			return nil, fmt.Errorf("creating receiver of organization %q: %w", org.Name, err)
+		}
+		a.organization = org.Name
+		a.telemetry = settings.TelemetrySettings
+		a.buildInfo = settings.BuildInfo
+
+		err = a.setMeterProvider(settings.MeterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("creating filter metrics of organization %q: %w", org.Name, err)
+		}
+
+		m.receivers = append(m.receivers, a)
+	}
+
+	return m, nil
+}
+
+func (m *multiOrganizationReceiver) Start(ctx context.Context, host component.Host) error {
+	for i, a := range m.receivers {
+		err := a.Start(ctx, host)
+		if err != nil {
+			// Receivers which are already started are stopped, so failed start does not leave them polling.
+			for _, started := range m.receivers[:i] {
+				_ = started.Shutdown(ctx)
+			}
+			return fmt.Errorf("starting receiver of organization %q: %w", a.organization, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *multiOrganizationReceiver) Shutdown(ctx context.Context) error {
+	// Receivers are shut down concurrently, so every one of them can finish its current page within the deadline.
+	errs := make([]error, len(m.receivers))
+	var wg sync.WaitGroup
+	for i, a := range m.receivers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := a.Shutdown(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("shutting down receiver of organization %q: %w", a.organization, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// organizationConfig derives configuration of a single organization: its API key and filters are used, and
+// persistent storage gets its own file so every organization has separate checkpoints.
+func organizationConfig(cfg *Config, org OrganizationConfig) *Config {
+	orgCfg := *cfg
+	orgCfg.Organizations = nil
+	orgCfg.API.Key = org.Key
+	if org.Filters.ClusterID != nil {
+		orgCfg.Filters.ClusterID = org.Filters.ClusterID
+	}
+	if org.Filters.Expression != "" || len(org.Filters.Rules) > 0 {
+		orgCfg.Filters.Expression = org.Filters.Expression
+		orgCfg.Filters.Rules = org.Filters.Rules
+	}
+	if !org.Filters.InitiatedBy.Include.empty() || !org.Filters.InitiatedBy.Exclude.empty() {
+		orgCfg.Filters.InitiatedBy = org.Filters.InitiatedBy
+	}
+	if len(org.Filters.Labels) > 0 {
+		orgCfg.Filters.Labels = org.Filters.Labels
+	}
+
+	orgCfg.Storage = maps.Clone(cfg.Storage)
+	if filename, ok := orgCfg.Storage["filename"].(string); ok && filename != "" {
+		orgCfg.Storage["filename"] = organizationFilename(filename, org.Name)
+	}
+
+	return &orgCfg
+}
+
+// organizationFilename inserts organization name before file extension, for example poll_data.json of organization
+// prod becomes poll_data.prod.json.
+func organizationFilename(filename, organization string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "." + organization + ext
+}
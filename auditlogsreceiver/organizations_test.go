@@ -0,0 +1,193 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+)
+
+func TestOrganizationConfig(t *testing.T) {
+	t.Run("when organization is configured then its key, filters and poll data file are used", func(t *testing.T) {
+		r := require.New(t)
+
+		clusterID := uuid.NewString()
+		cfg := &Config{
+			API: API{
+				Url: "https://api.cast.ai",
+			},
+			Filters: FilterConfig{
+				ClusterID:  lo.ToPtr(uuid.NewString()),
+				Expression: `item.eventType == "clusterDeleted"`,
+			},
+			Storage: map[string]interface{}{
+				"type":     "persistent",
+				"filename": "/var/lib/castai/poll_data.json",
+			},
+			Organizations: []OrganizationConfig{
+				{Name: "prod", Key: "prod-key", Filters: FilterConfig{ClusterID: &clusterID}},
+				{Name: "dev", Key: "dev-key"},
+			},
+		}
+
+		prod := organizationConfig(cfg, cfg.Organizations[0])
+		r.Equal("prod-key", prod.API.Key)
+		r.Equal(clusterID, *prod.Filters.ClusterID)
+		r.Equal(cfg.Filters.Expression, prod.Filters.Expression)
+		r.Equal("/var/lib/castai/poll_data.prod.json", prod.Storage["filename"])
+		r.Empty(prod.Organizations)
+
+		dev := organizationConfig(cfg, cfg.Organizations[1])
+		r.Equal("dev-key", dev.API.Key)
+		r.Equal(cfg.Filters, dev.Filters)
+		r.Equal("/var/lib/castai/poll_data.dev.json", dev.Storage["filename"])
+
+		// Receiver's configuration must stay untouched.
+		r.Equal("/var/lib/castai/poll_data.json", cfg.Storage["filename"])
+		r.Empty(cfg.API.Key)
+	})
+
+	t.Run("when organization has expression filters then they replace receiver's ones and others are kept", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := &Config{
+			Filters: FilterConfig{
+				Expression: `item.eventType == "clusterDeleted"`,
+				InitiatedBy: InitiatedByFilterConfig{
+					Exclude: InitiatorMatcher{ActorTypes: []string{actorTypeSystem}},
+				},
+			},
+			Storage: map[string]interface{}{
+				"type": "in-memory",
+			},
+			Organizations: []OrganizationConfig{
+				{Name: "prod", Key: "prod-key", Filters: FilterConfig{
+					Rules: []FilterRule{{Name: "deletions", Expression: `item.eventType.endsWith("Deleted")`}},
+				}},
+			},
+		}
+
+		prod := organizationConfig(cfg, cfg.Organizations[0])
+		r.Nil(prod.Filters.ClusterID)
+		r.Empty(prod.Filters.Expression)
+		r.Equal(cfg.Organizations[0].Filters.Rules, prod.Filters.Rules)
+		r.Equal(cfg.Filters.InitiatedBy, prod.Filters.InitiatedBy)
+	})
+}
+
+func TestOrganizationFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "poll_data.json", want: "poll_data.prod.json"},
+		{filename: "/var/lib/castai/poll_data", want: "/var/lib/castai/poll_data.prod"},
+		{filename: "./state/poll.data.json", want: "./state/poll.data.prod.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			require.Equal(t, tt.want, organizationFilename(tt.filename, "prod"))
+		})
+	}
+}
+
+func TestMultiOrganizationReceiver(t *testing.T) {
+	t.Run("when several organizations are configured then audit logs of every one are polled and tagged", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(newResponseWithOneItem(time.Now())))
+		}))
+		defer server.Close()
+
+		var (
+			mu            sync.Mutex
+			organizations = map[string]string{}
+			received      = make(chan struct{}, 2)
+		)
+		consumer := logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				organization, _ := logs.ResourceLogs().At(0).Resource().Attributes().Get(organizationAttribute)
+				organizations[organization.Str()] = ""
+				select {
+				case received <- struct{}{}:
+				default:
+				}
+				return nil
+			},
+		}
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Url = server.URL
+		cfg.PollInterval = time.Hour
+		cfg.Storage = map[string]interface{}{
+			"type":     "persistent",
+			"filename": filepath.Join(t.TempDir(), "poll_data.json"),
+		}
+		cfg.Organizations = []OrganizationConfig{
+			{Name: "prod", Key: uuid.NewString()},
+			{Name: "dev", Key: uuid.NewString()},
+		}
+		r.NoError(cfg.Validate())
+
+		rcv, err := NewAuditLogsReceiver(ctx, receivertest.NewNopSettings(metadata.Type), cfg, consumer)
+		r.NoError(err)
+		r.IsType(&multiOrganizationReceiver{}, rcv)
+
+		r.NoError(rcv.Start(ctx, componenttest.NewNopHost()))
+		<-received
+		<-received
+		r.NoError(rcv.Shutdown(ctx))
+
+		r.Equal(map[string]string{"prod": "", "dev": ""}, organizations)
+		r.FileExists(organizationFilename(cfg.Storage["filename"].(string), "prod"))
+		r.FileExists(organizationFilename(cfg.Storage["filename"].(string), "dev"))
+	})
+
+	t.Run("when receiver of one organization fails to start then receivers of others are stopped", func(t *testing.T) {
+		r := require.New(t)
+		ctx := context.Background()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.API.Url = server.URL
+		cfg.PollInterval = time.Hour
+		cfg.Storage = map[string]interface{}{
+			"type": "in-memory",
+		}
+		cfg.Organizations = []OrganizationConfig{
+			{Name: "prod", Key: uuid.NewString()},
+			{Name: "dev", Key: uuid.NewString()},
+		}
+
+		rcv, err := NewAuditLogsReceiver(ctx, receivertest.NewNopSettings(metadata.Type), cfg, logsConsumerMock{})
+		r.NoError(err)
+
+		// Making API client of the second organization fail to build.
+		m := rcv.(*multiOrganizationReceiver)
+		m.receivers[1].api.TLS.CAFile = filepath.Join(t.TempDir(), "missing-ca.pem")
+
+		r.Error(rcv.Start(ctx, componenttest.NewNopHost()))
+		// Goroutine leaks of the first receiver are caught by goleak in TestMain.
+	})
+}
@@ -0,0 +1,150 @@
+package auditlogsreceiver
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	outputProfileSplunk  = "splunk"
+	outputProfileDatadog = "datadog"
+
+	// Attributes used by Splunk HEC exporter as event metadata instead of its configured defaults.
+	splunkSourceAttribute     = "com.splunk.source"
+	splunkSourceTypeAttribute = "com.splunk.sourcetype"
+	splunkIndexAttribute      = "com.splunk.index"
+
+	// Attributes treated by Datadog Log Management as reserved ones.
+	datadogSourceAttribute  = "ddsource"
+	datadogServiceAttribute = "service"
+	datadogTagsAttribute    = "ddtags"
+	datadogStatusAttribute  = "status"
+)
+
+// datadogSeverities maps Datadog statuses to severity numbers of log records.
+var datadogSeverities = map[string]plog.SeverityNumber{
+	"emergency": plog.SeverityNumberFatal4,
+	"alert":     plog.SeverityNumberFatal2,
+	"critical":  plog.SeverityNumberFatal,
+	"error":     plog.SeverityNumberError,
+	"warning":   plog.SeverityNumberWarn,
+	"notice":    plog.SeverityNumberInfo2,
+	"info":      plog.SeverityNumberInfo,
+	"debug":     plog.SeverityNumberDebug,
+}
+
+// outputProfile shapes log records for a specific destination, so the pipeline needs no processors.
+type outputProfile interface {
+	// apply adjusts log record built from the item and its resource; it is called before the record is linked to
+	// the hash chain.
+	apply(resource pcommon.Resource, record plog.LogRecord, item map[string]interface{}) error
+	// timestamp adjusts audit log time to precision supported by the destination.
+	timestamp(t time.Time) time.Time
+}
+
+func newOutputProfile(cfg *Config) outputProfile {
+	switch cfg.OutputProfile {
+	case outputProfileSplunk:
+		return &splunkProfile{cfg: cfg.Splunk}
+	case outputProfileDatadog:
+		return &datadogProfile{cfg: cfg.Datadog}
+	default:
+		return nil
+	}
+}
+
+// splunkProfile makes records ready for Splunk HEC exporter: the body holds the whole audit event, which Splunk
+// shows as the event, and attributes hint event's source, source type and index. HEC exporter sends attributes as
+// indexed fields, which must be flat, so nested ones are dropped; their values are in the body already.
+type splunkProfile struct {
+	cfg SplunkProfileConfig
+}
+
+func (p *splunkProfile) apply(_ pcommon.Resource, record plog.LogRecord, item map[string]interface{}) error {
+	err := record.Body().SetEmptyMap().FromRaw(item)
+	if err != nil {
+		return err
+	}
+
+	attributes := record.Attributes()
+	attributes.RemoveIf(func(_ string, value pcommon.Value) bool {
+		switch value.Type() {
+		case pcommon.ValueTypeMap, pcommon.ValueTypeSlice, pcommon.ValueTypeEmpty:
+			return true
+		default:
+			return false
+		}
+	})
+	attributes.PutStr(splunkSourceAttribute, p.cfg.Source)
+	attributes.PutStr(splunkSourceTypeAttribute, p.cfg.SourceType)
+	if p.cfg.Index != "" {
+		attributes.PutStr(splunkIndexAttribute, p.cfg.Index)
+	}
+
+	return nil
+}
+
+// timestamp truncates time to milliseconds, which is the precision of HEC event time.
+func (p *splunkProfile) timestamp(t time.Time) time.Time {
+	return t.Truncate(time.Millisecond)
+}
+
+// datadogProfile makes records land categorized in Datadog Log Management: source, service, tags and status are set
+// using Datadog reserved attributes.
+type datadogProfile struct {
+	cfg DatadogProfileConfig
+}
+
+func (p *datadogProfile) apply(resource pcommon.Resource, record plog.LogRecord, item map[string]interface{}) error {
+	// Datadog exporter takes service of logs from the resource.
+	resource.Attributes().PutStr("service.name", p.cfg.Service)
+
+	attributes := record.Attributes()
+	attributes.PutStr(datadogSourceAttribute, p.cfg.Source)
+	attributes.PutStr(datadogServiceAttribute, p.cfg.Service)
+	if tags := datadogTags(item); tags != "" {
+		attributes.PutStr(datadogTagsAttribute, tags)
+	}
+
+	eventType, _ := item["eventType"].(string)
+	status, ok := p.cfg.Statuses[eventType]
+	if !ok {
+		status = p.cfg.DefaultStatus
+	}
+	attributes.PutStr(datadogStatusAttribute, status)
+	record.SetSeverityText(status)
+	record.SetSeverityNumber(datadogSeverities[status])
+
+	return nil
+}
+
+func (p *datadogProfile) timestamp(t time.Time) time.Time {
+	return t
+}
+
+// datadogTags builds tags from cluster id, provider, region and event type of the audit log; missing ones are skipped.
+func datadogTags(item map[string]interface{}) string {
+	labels, _ := item["labels"].(map[string]interface{})
+	event, _ := item["event"].(map[string]interface{})
+	cluster, _ := event["cluster"].(map[string]interface{})
+
+	var tags []string
+	for _, tag := range []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "cluster_id", value: labels["clusterId"]},
+		{name: "provider", value: cluster["providerType"]},
+		{name: "region", value: cluster["region"]},
+		{name: "event_type", value: item["eventType"]},
+	} {
+		if value, _ := tag.value.(string); value != "" {
+			tags = append(tags, tag.name+":"+value)
+		}
+	}
+
+	return strings.Join(tags, ",")
+}
@@ -0,0 +1,154 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestSplunkProfile(t *testing.T) {
+	t.Run("when profile is applied then body holds the audit event and splunk metadata is hinted", func(t *testing.T) {
+		r := require.New(t)
+
+		profile := newOutputProfile(&Config{
+			OutputProfile: outputProfileSplunk,
+			Splunk: SplunkProfileConfig{
+				Source:     "castai",
+				SourceType: "castai:audit",
+				Index:      "audit",
+			},
+		})
+		r.NotNil(profile)
+
+		record := plog.NewLogRecord()
+		item := newTestItem(t)
+		r.NoError(profile.apply(pcommon.NewResource(), record, item))
+
+		r.Equal(item, record.Body().Map().AsRaw())
+		r.Equal(map[string]interface{}{
+			splunkSourceAttribute:     "castai",
+			splunkSourceTypeAttribute: "castai:audit",
+			splunkIndexAttribute:      "audit",
+		}, record.Attributes().AsRaw())
+	})
+
+	t.Run("when index is not configured then it is not hinted", func(t *testing.T) {
+		r := require.New(t)
+
+		profile := newOutputProfile(&Config{
+			OutputProfile: outputProfileSplunk,
+			Splunk: SplunkProfileConfig{
+				Source:     "castai",
+				SourceType: "castai:audit",
+			},
+		})
+
+		record := plog.NewLogRecord()
+		r.NoError(profile.apply(pcommon.NewResource(), record, newTestItem(t)))
+
+		_, ok := record.Attributes().Get(splunkIndexAttribute)
+		r.False(ok)
+	})
+
+	t.Run("when timestamp has sub-millisecond precision then it is truncated to milliseconds", func(t *testing.T) {
+		r := require.New(t)
+
+		tm := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+		r.Equal(time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC), (&splunkProfile{}).timestamp(tm))
+	})
+
+	t.Run("when audit logs are processed with splunk profile then records are ready for splunk hec exporter", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.OutputProfile = outputProfileSplunk
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger:  zap.L(),
+			profile: newOutputProfile(cfg),
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		auditLogTime := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(auditLogTime)), &auditLogsMap))
+		_, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		record := exported.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+		r.Equal(auditLogsMap["items"].([]interface{})[0], record.Body().Map().AsRaw())
+		// Nested attributes are dropped, as HEC indexed fields must be flat and the body holds them already.
+		r.Equal(map[string]interface{}{
+			"id":                      "824e7a47-b8e3-430e-8a7d-e9db83781e6e",
+			"eventType":               "clusterDeleted",
+			splunkSourceAttribute:     cfg.Splunk.Source,
+			splunkSourceTypeAttribute: "castai:audit",
+		}, record.Attributes().AsRaw())
+		r.Equal(auditLogTime.Truncate(time.Millisecond), record.Timestamp().AsTime())
+	})
+}
+
+func TestDatadogProfile(t *testing.T) {
+	t.Run("when profile is applied then source, service, tags and mapped status are set", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.OutputProfile = outputProfileDatadog
+		cfg.Datadog.Statuses = map[string]string{
+			"clusterDeleted": "warning",
+		}
+		profile := newOutputProfile(cfg)
+		r.NotNil(profile)
+
+		var item map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(time.Now())), &item))
+		item = item["items"].([]interface{})[0].(map[string]interface{})
+
+		resource := pcommon.NewResource()
+		record := plog.NewLogRecord()
+		r.NoError(profile.apply(resource, record, item))
+
+		serviceName, ok := resource.Attributes().Get("service.name")
+		r.True(ok)
+		r.Equal("castai-audit-logs", serviceName.Str())
+		r.Equal(map[string]interface{}{
+			datadogSourceAttribute:  "castai",
+			datadogServiceAttribute: "castai-audit-logs",
+			datadogTagsAttribute:    "cluster_id:1e6e37e0-7a06-4fde-8eb0-019ae8b1cf4f,provider:gke,region:europe-west1,event_type:clusterDeleted",
+			datadogStatusAttribute:  "warning",
+		}, record.Attributes().AsRaw())
+		r.Equal("warning", record.SeverityText())
+		r.Equal(plog.SeverityNumberWarn, record.SeverityNumber())
+	})
+
+	t.Run("when event type is not mapped then default status is set and missing tags are skipped", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := newDefaultConfig().(*Config)
+		cfg.OutputProfile = outputProfileDatadog
+		profile := newOutputProfile(cfg)
+
+		record := plog.NewLogRecord()
+		r.NoError(profile.apply(pcommon.NewResource(), record, newTestItem(t)))
+
+		tags, ok := record.Attributes().Get(datadogTagsAttribute)
+		r.True(ok)
+		r.Equal("event_type:apiKeyCreated", tags.Str())
+		status, ok := record.Attributes().Get(datadogStatusAttribute)
+		r.True(ok)
+		r.Equal("info", status.Str())
+		r.Equal(plog.SeverityNumberInfo, record.SeverityNumber())
+	})
+}
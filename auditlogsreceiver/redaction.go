@@ -0,0 +1,166 @@
+package auditlogsreceiver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	redactionActionDrop = "drop"
+	redactionActionMask = "mask"
+	redactionActionHash = "hash"
+
+	// redactionMask replaces values of paths configured with the mask action.
+	redactionMask = "***"
+	// redactionPathWildcard matches any key on the given level of the path.
+	redactionPathWildcard = "*"
+	// redactionOmittedSnippet replaces a part of response body which cannot be redacted.
+	redactionOmittedSnippet = "(omitted, as it cannot be redacted)"
+)
+
+type redactionRule struct {
+	path   []string
+	action string
+}
+
+// redactor removes or obfuscates sensitive values (emails, names, IP addresses, etc.) in audit log items
+// before they are converted into log records.
+type redactor struct {
+	rules []redactionRule
+	salt  []byte
+}
+
+func newRedactor(cfg RedactionConfig) (*redactor, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	r := &redactor{}
+	for _, rule := range cfg.Rules {
+		r.rules = append(r.rules, redactionRule{
+			path:   strings.Split(rule.Path, "."),
+			action: rule.Action,
+		})
+	}
+
+	if cfg.SaltFile != "" {
+		salt, err := os.ReadFile(cfg.SaltFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading redaction salt file: %w", err)
+		}
+
+		// Trailing new lines are common in files created by hand or mounted from secrets, so they are not part of the salt.
+		r.salt = bytes.TrimSpace(salt)
+		if len(r.salt) == 0 {
+			return nil, errors.New("redaction salt file is empty")
+		}
+	}
+
+	return r, nil
+}
+
+// redact applies redaction rules to the item in place. It is safe to call on nil redactor.
+func (r *redactor) redact(item map[string]interface{}) {
+	if r == nil {
+		return
+	}
+
+	for _, rule := range r.rules {
+		r.apply(item, rule.path, rule.action)
+	}
+}
+
+// redactSnippet applies redaction rules to a part of response body shown in errors and logs, either to the object
+// itself or to items of a page. A snippet which is not a complete JSON object (for example, a truncated page) cannot be
+// redacted, so it is omitted. It is safe to call on nil redactor, which returns the snippet as is.
+func (r *redactor) redactSnippet(snippet string) string {
+	if r == nil {
+		return snippet
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(snippet), &body); err != nil {
+		return redactionOmittedSnippet
+	}
+	if items, ok := body["items"].([]interface{}); ok {
+		for _, it := range items {
+			if item, ok := it.(map[string]interface{}); ok {
+				r.redact(item)
+			}
+		}
+	} else {
+		r.redact(body)
+	}
+
+	redacted, err := json.Marshal(body)
+	if err != nil {
+		return redactionOmittedSnippet
+	}
+	return string(redacted)
+}
+
+func (r *redactor) apply(node interface{}, path []string, action string) {
+	switch n := node.(type) {
+	case []interface{}:
+		// Arrays are transparent for paths, so rule is applied to every element.
+		for _, el := range n {
+			r.apply(el, path, action)
+		}
+	case map[string]interface{}:
+		key := path[0]
+		if key == redactionPathWildcard {
+			for k := range n {
+				r.applyToKey(n, k, path, action)
+			}
+			return
+		}
+
+		if _, ok := n[key]; ok {
+			r.applyToKey(n, key, path, action)
+		}
+	}
+}
+
+func (r *redactor) applyToKey(m map[string]interface{}, key string, path []string, action string) {
+	if len(path) > 1 {
+		r.apply(m[key], path[1:], action)
+		return
+	}
+
+	switch action {
+	case redactionActionDrop:
+		delete(m, key)
+	case redactionActionMask:
+		if m[key] != nil {
+			m[key] = redactionMask
+		}
+	case redactionActionHash:
+		if m[key] != nil {
+			m[key] = r.hash(m[key])
+		}
+	}
+}
+
+// hash produces deterministic HMAC-SHA256 digest of the value, so the same value (for example, the same email)
+// always maps to the same digest and records can still be correlated.
+func (r *redactor) hash(value interface{}) string {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	default:
+		// Marshalling maps produces sorted keys, so digest of non-scalar values is deterministic as well.
+		data, _ = json.Marshal(v)
+	}
+
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
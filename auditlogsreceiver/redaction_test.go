@@ -0,0 +1,197 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func newTestItem(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	var item map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"id": "824e7a47-b8e3-430e-8a7d-e9db83781e6e",
+		"eventType": "apiKeyCreated",
+		"initiatedBy": {
+			"id": "google-oauth2|100187903622338083673",
+			"name": "John Doe",
+			"email": "john@example.com"
+		},
+		"event": {
+			"login": {"ipAddress": "10.0.0.1"},
+			"sessions": [{"ipAddress": "10.0.0.2"}, {"ipAddress": "10.0.0.3"}]
+		}
+	}`), &item)
+	require.NoError(t, err)
+
+	return item
+}
+
+func writeSaltFile(t *testing.T, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "salt")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0600))
+
+	return filename
+}
+
+func TestRedactor(t *testing.T) {
+	t.Run("when redactor is not configured then item is left untouched", func(t *testing.T) {
+		r := require.New(t)
+
+		redactor, err := newRedactor(RedactionConfig{})
+		r.NoError(err)
+		r.Nil(redactor)
+
+		item := newTestItem(t)
+		redactor.redact(item)
+		r.Equal(newTestItem(t), item)
+	})
+
+	t.Run("when drop and mask rules are configured then values are removed or masked", func(t *testing.T) {
+		r := require.New(t)
+
+		redactor, err := newRedactor(RedactionConfig{
+			Rules: []RedactionRule{
+				{Path: "initiatedBy.email", Action: redactionActionDrop},
+				{Path: "initiatedBy.name", Action: redactionActionMask},
+				{Path: "initiatedBy.missing", Action: redactionActionMask},
+			},
+		})
+		r.NoError(err)
+
+		item := newTestItem(t)
+		redactor.redact(item)
+
+		initiatedBy := item["initiatedBy"].(map[string]interface{})
+		r.NotContains(initiatedBy, "email")
+		r.NotContains(initiatedBy, "missing")
+		r.Equal(redactionMask, initiatedBy["name"])
+		r.Equal("google-oauth2|100187903622338083673", initiatedBy["id"])
+	})
+
+	t.Run("when response body snippet is redacted then only complete objects are shown", func(t *testing.T) {
+		r := require.New(t)
+
+		var noRedactor *redactor
+		r.Equal(`{"items": [`, noRedactor.redactSnippet(`{"items": [`))
+
+		redactor, err := newRedactor(RedactionConfig{
+			Rules: []RedactionRule{{Path: "initiatedBy.email", Action: redactionActionMask}},
+		})
+		r.NoError(err)
+
+		r.Equal(`{"items":[{"initiatedBy":{"email":"***"}}]}`, redactor.redactSnippet(`{"items": [{"initiatedBy": {"email": "andrej@cast.ai"}}]}`))
+		r.Equal(`{"initiatedBy":{"email":"***"}}`, redactor.redactSnippet(`{"initiatedBy": {"email": "andrej@cast.ai"}}`))
+		r.Equal(redactionOmittedSnippet, redactor.redactSnippet(`{"items": [{"initiatedBy": {"email": "andrej@cast.ai"`))
+	})
+
+	t.Run("when wildcard path is configured then values on every key and array element are redacted", func(t *testing.T) {
+		r := require.New(t)
+
+		redactor, err := newRedactor(RedactionConfig{
+			Rules: []RedactionRule{
+				{Path: "event.*.ipAddress", Action: redactionActionMask},
+			},
+		})
+		r.NoError(err)
+
+		item := newTestItem(t)
+		redactor.redact(item)
+
+		event := item["event"].(map[string]interface{})
+		r.Equal(redactionMask, event["login"].(map[string]interface{})["ipAddress"])
+		for _, session := range event["sessions"].([]interface{}) {
+			r.Equal(redactionMask, session.(map[string]interface{})["ipAddress"])
+		}
+	})
+
+	t.Run("when hash rule is configured then values are hashed deterministically using salt", func(t *testing.T) {
+		r := require.New(t)
+
+		cfg := RedactionConfig{
+			SaltFile: writeSaltFile(t, "secret\n"),
+			Rules: []RedactionRule{
+				{Path: "initiatedBy.email", Action: redactionActionHash},
+			},
+		}
+		redactor, err := newRedactor(cfg)
+		r.NoError(err)
+
+		first, second := newTestItem(t), newTestItem(t)
+		redactor.redact(first)
+		redactor.redact(second)
+
+		hashed := first["initiatedBy"].(map[string]interface{})["email"]
+		r.NotEqual("john@example.com", hashed)
+		r.Len(hashed, 64)
+		r.Equal(hashed, second["initiatedBy"].(map[string]interface{})["email"])
+
+		// Different salt must produce different digest.
+		cfg.SaltFile = writeSaltFile(t, "another secret")
+		redactor, err = newRedactor(cfg)
+		r.NoError(err)
+
+		third := newTestItem(t)
+		redactor.redact(third)
+		r.NotEqual(hashed, third["initiatedBy"].(map[string]interface{})["email"])
+	})
+
+	t.Run("when salt file is empty then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		_, err := newRedactor(RedactionConfig{
+			SaltFile: writeSaltFile(t, " \n"),
+			Rules: []RedactionRule{
+				{Path: "initiatedBy.email", Action: redactionActionHash},
+			},
+		})
+		r.Error(err)
+	})
+
+	t.Run("when audit logs are processed then redacted values are exported", func(t *testing.T) {
+		r := require.New(t)
+
+		redactor, err := newRedactor(RedactionConfig{
+			Rules: []RedactionRule{
+				{Path: "initiatedBy.email", Action: redactionActionDrop},
+			},
+		})
+		r.NoError(err)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger:   zap.L(),
+			redactor: redactor,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(time.Now())), &auditLogsMap))
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		r.Equal(1, exported.LogRecordCount())
+		attributes := exported.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes()
+		initiatedBy, ok := attributes.Get("initiatedBy")
+		r.True(ok)
+		_, ok = initiatedBy.Map().Get("email")
+		r.False(ok)
+		_, ok = initiatedBy.Map().Get("name")
+		r.True(ok)
+	})
+}
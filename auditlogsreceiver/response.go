@@ -0,0 +1,217 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// defaultMaxResponseSize limits size of a page of audit logs, unless configured otherwise.
+	defaultMaxResponseSize = 64 << 20
+	// bodySnippetSize limits part of response body included in errors.
+	bodySnippetSize = 512
+)
+
+var errResponseTooLarge = errors.New("response exceeds max response size")
+
+// itemError is an error of converting a decoded item, as opposed to an error of decoding response body.
+type itemError struct {
+	err error
+}
+
+func (e itemError) Error() string {
+	return e.err.Error()
+}
+
+func (e itemError) Unwrap() error {
+	return e.err
+}
+
+// page summarizes a page of audit logs decoded from response body.
+type page struct {
+	items                 int
+	nextCursor            string
+	lastAuditLogTimestamp *time.Time
+}
+
+// limitedReader fails when there is anything to read beyond n bytes, unlike io.LimitedReader which silently stops at
+// the limit.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Probing whether anything is left beyond the limit.
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, errResponseTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// snippetWriter keeps the beginning of what is written to it, so errors can show a part of response body.
+type snippetWriter struct {
+	buf []byte
+}
+
+func (w *snippetWriter) Write(p []byte) (int, error) {
+	if room := bodySnippetSize - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (w *snippetWriter) String() string {
+	if len(w.buf) == bodySnippetSize {
+		return string(w.buf) + "..."
+	}
+	return string(w.buf)
+}
+
+// readSnippet reads the beginning of body, for example of an error response.
+func readSnippet(body io.Reader) string {
+	w := &snippetWriter{}
+	_, _ = io.Copy(w, io.LimitReader(body, bodySnippetSize+1))
+	return w.String()
+}
+
+// processResponseBody decodes a page of audit logs from response body as a stream, converting items one by one to log
+// records of the batch, so the whole page is never held in memory. Pages of the batch are dropped on failure, as their
+// position was not saved and they are polled again.
+//
+// The batch is owned by the caller, so only converting of an item locks processMu; reading response body does not
+// block audit logs received via webhook.
+func (a *auditLogsReceiver) processResponseBody(ctx context.Context, batch *logsBatch, body io.Reader) (page, error) {
+	batch.addPage()
+
+	maxSize := a.maxResponseSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxResponseSize
+	}
+	snippet := &snippetWriter{}
+	decoder := json.NewDecoder(io.TeeReader(&limitedReader{r: body, n: maxSize}, snippet))
+
+	p, err := a.decodePage(ctx, batch, decoder)
+	if err != nil {
+		batch.reset()
+		var ie itemError
+		if errors.As(err, &ie) {
+			return page{}, fmt.Errorf("processing audit logs items: %w", ie.err)
+		}
+		if errors.Is(err, errResponseTooLarge) {
+			return page{}, fmt.Errorf("%w of %d bytes", err, maxSize)
+		}
+		return page{}, fmt.Errorf("unexpected body in response: %w, body: %s", err, a.redactor.redactSnippet(snippet.String()))
+	}
+
+	return p, nil
+}
+
+func (a *auditLogsReceiver) decodePage(ctx context.Context, batch *logsBatch, decoder *json.Decoder) (p page, err error) {
+	if err = expectDelim(decoder, '{'); err != nil {
+		return p, err
+	}
+
+	for decoder.More() {
+		var key string
+		if key, err = decodeKey(decoder); err != nil {
+			return p, err
+		}
+
+		switch key {
+		case "items":
+			err = a.decodeItems(ctx, batch, decoder, &p)
+		case "nextCursor":
+			var cursor interface{}
+			err = decoder.Decode(&cursor)
+			if err == nil {
+				var ok bool
+				p.nextCursor, ok = cursor.(string)
+				if !ok {
+					a.logger.Warn("invalid cursor type is returned, skipping")
+				}
+			}
+		default:
+			// Other fields are skipped without being kept.
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+
+	return p, expectDelim(decoder, '}')
+}
+
+func (a *auditLogsReceiver) decodeItems(ctx context.Context, batch *logsBatch, decoder *json.Decoder, p *page) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		a.logger.Warn("no audit logs items found in the response, skipping")
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("invalid items type in the response: %v", token)
+	}
+
+	for decoder.More() {
+		var item interface{}
+		if err = decoder.Decode(&item); err != nil {
+			return err
+		}
+		p.items++
+
+		a.processMu.Lock()
+		auditLogTimestamp, err := a.appendAuditLog(ctx, batch, item)
+		a.processMu.Unlock()
+		if err != nil {
+			return itemError{err: err}
+		}
+		if auditLogTimestamp != nil {
+			p.lastAuditLogTimestamp = auditLogTimestamp
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, token)
+	}
+	return nil
+}
+
+func decodeKey(decoder *json.Decoder) (string, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", token)
+	}
+	return key, nil
+}
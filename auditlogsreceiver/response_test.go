@@ -0,0 +1,205 @@
+package auditlogsreceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func newStreamingReceiver(consumed *int) *auditLogsReceiver {
+	return &auditLogsReceiver{
+		logger: zap.L(),
+		consumer: logsConsumerMock{
+			ConsumeLogsFunc: func(logs plog.Logs) error {
+				*consumed += logs.LogRecordCount()
+				return nil
+			},
+		},
+	}
+}
+
+func TestProcessResponseBody(t *testing.T) {
+	t.Run("when page is decoded then items are converted to records of the batch and cursor is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+		lastLogTimestamp := time.Now().Truncate(time.Millisecond)
+
+		body := `{"total": 2, "unknown": {"nested": [1, 2]}, ` + strings.TrimPrefix(newResponseWithTwoItem(lastLogTimestamp, "cursor-2"), "{")
+		batch := &logsBatch{}
+		p, err := receiver.processResponseBody(context.Background(), batch, strings.NewReader(body))
+		r.NoError(err)
+		r.Equal(2, p.items)
+		r.Equal("cursor-2", p.nextCursor)
+		r.NotNil(p.lastAuditLogTimestamp)
+		r.True(lastLogTimestamp.Equal(*p.lastAuditLogTimestamp))
+		r.Equal(2, batch.logs.LogRecordCount())
+
+		r.NoError(receiver.flush(context.Background(), batch))
+		r.Equal(2, consumed)
+	})
+
+	t.Run("when page has no items then nothing is converted", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+
+		for _, body := range []string{`{}`, `{"items": null}`, `{"items": []}`} {
+			p, err := receiver.processResponseBody(context.Background(), &logsBatch{}, strings.NewReader(body))
+			r.NoError(err)
+			r.Zero(p.items)
+			r.Empty(p.nextCursor)
+			r.Nil(p.lastAuditLogTimestamp)
+		}
+	})
+
+	t.Run("when response exceeds max response size then it is refused", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+		body := newResponseWithItems(t, 100, time.Now())
+		receiver.maxResponseSize = int64(len(body) - 1)
+
+		batch := &logsBatch{}
+		_, err := receiver.processResponseBody(context.Background(), batch, bytes.NewReader(body))
+		r.ErrorIs(err, errResponseTooLarge)
+		r.Zero(batch.pages)
+
+		receiver.maxResponseSize = int64(len(body))
+		p, err := receiver.processResponseBody(context.Background(), &logsBatch{}, bytes.NewReader(body))
+		r.NoError(err)
+		r.Equal(100, p.items)
+	})
+
+	t.Run("when body is malformed then error holds truncated body", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+		body := `{"items": [{"id": "` + strings.Repeat("x", 10*bodySnippetSize) + `"}, }`
+
+		_, err := receiver.processResponseBody(context.Background(), &logsBatch{}, strings.NewReader(body))
+		r.ErrorContains(err, "unexpected body in response")
+		r.Less(len(err.Error()), 2*bodySnippetSize)
+		r.Contains(err.Error(), `{"items": [{"id": "xxx`)
+	})
+
+	t.Run("when body is malformed and redaction is configured then error does not hold sensitive values", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+		var err error
+		receiver.redactor, err = newRedactor(RedactionConfig{
+			Rules: []RedactionRule{{Path: "initiatedBy.email", Action: redactionActionDrop}},
+		})
+		r.NoError(err)
+		body := `{"items": [{"initiatedBy": {"email": "andrej@cast.ai"}}, }`
+
+		_, err = receiver.processResponseBody(context.Background(), &logsBatch{}, strings.NewReader(body))
+		r.ErrorContains(err, "unexpected body in response")
+		r.ErrorContains(err, redactionOmittedSnippet)
+		r.NotContains(err.Error(), "andrej@cast.ai")
+	})
+
+	t.Run("when items are not an array then error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+
+		_, err := receiver.processResponseBody(context.Background(), &logsBatch{}, strings.NewReader(`{"items": {"id": "1"}}`))
+		r.ErrorContains(err, "invalid items type")
+	})
+
+	t.Run("when response body is being read then audit logs received via webhook are not blocked", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		receiver := newStreamingReceiver(&consumed)
+		item := func() string {
+			return fmt.Sprintf(`{"id": %q, "eventType": "nodeAdded", "time": %q}`, uuid.NewString(), time.Now().UTC().Format(timestampLayout))
+		}
+
+		bodyReader, bodyWriter := io.Pipe()
+		batch := &logsBatch{}
+		polled := make(chan error, 1)
+		go func() {
+			_, err := receiver.processResponseBody(context.Background(), batch, bodyReader)
+			polled <- err
+		}()
+		// Write returns once the first item is read, the rest of the page is not sent yet.
+		_, err := bodyWriter.Write([]byte(`{"items": [` + item() + `,`))
+		r.NoError(err)
+
+		received := make(chan error, 1)
+		go func() {
+			var auditLogsMap map[string]interface{}
+			if err := json.Unmarshal([]byte(`{"items": [`+item()+`]}`), &auditLogsMap); err != nil {
+				received <- err
+				return
+			}
+			_, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+			received <- err
+		}()
+		select {
+		case err = <-received:
+			r.NoError(err)
+		case <-time.After(5 * time.Second):
+			r.FailNow("webhook audit logs are blocked by response body being read")
+		}
+		r.Equal(1, consumed)
+
+		_, err = bodyWriter.Write([]byte(item() + `]}`))
+		r.NoError(err)
+		r.NoError(bodyWriter.Close())
+		r.NoError(<-polled)
+
+		r.NoError(receiver.flush(context.Background(), batch))
+		r.Equal(3, consumed)
+	})
+}
+
+func TestReadSnippet(t *testing.T) {
+	t.Run("when body is short then it is returned whole", func(t *testing.T) {
+		require.Equal(t, `{"message": "forbidden"}`, readSnippet(strings.NewReader(`{"message": "forbidden"}`)))
+	})
+
+	t.Run("when body is long then it is truncated", func(t *testing.T) {
+		snippet := readSnippet(strings.NewReader(strings.Repeat("x", 10*bodySnippetSize)))
+		require.Equal(t, strings.Repeat("x", bodySnippetSize)+"...", snippet)
+	})
+}
+
+func BenchmarkProcessResponseBody(b *testing.B) {
+	consumed := 0
+	receiver := newStreamingReceiver(&consumed)
+	receiver.logger = zap.NewNop()
+	body := newResponseWithItems(b, 1000, time.Now())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch := &logsBatch{}
+		_, err := receiver.processResponseBody(context.Background(), batch, bytes.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = receiver.flush(context.Background(), batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package auditlogsreceiver
+
+import (
+	"path"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/chain"
+)
+
+// routeAttribute is a resource attribute naming route of the audit log, used by routing connector to pick pipelines;
+// it is set on records as well, so hash chain of every route can be verified.
+const routeAttribute = chain.RouteAttribute
+
+type route struct {
+	name       string
+	eventTypes []string
+	labels     []labelMatcher
+}
+
+func (r route) matches(item map[string]interface{}) bool {
+	if len(r.eventTypes) > 0 {
+		eventType, _ := item["eventType"].(string)
+		matched := false
+		for _, pattern := range r.eventTypes {
+			if ok, _ := path.Match(pattern, eventType); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return matchLabels(r.labels, item)
+}
+
+// router names route of audit log items, so a single receiver can feed separate pipelines via routing connector.
+type router struct {
+	routes       []route
+	defaultRoute string
+}
+
+func newRouter(cfg RoutingConfig) (*router, error) {
+	if len(cfg.Routes) == 0 {
+		return nil, nil
+	}
+
+	r := &router{defaultRoute: cfg.Default}
+	for _, rc := range cfg.Routes {
+		labels, err := newLabelMatchers(rc.Labels)
+		if err != nil {
+			return nil, err
+		}
+		r.routes = append(r.routes, route{
+			name:       rc.Name,
+			eventTypes: rc.EventTypes,
+			labels:     labels,
+		})
+	}
+
+	return r, nil
+}
+
+// route returns name of the first route matching the item, or the default one. It is safe to call on nil router,
+// which returns no route.
+func (r *router) route(item map[string]interface{}) string {
+	if r == nil {
+		return ""
+	}
+
+	for _, route := range r.routes {
+		if route.matches(item) {
+			return route.name
+		}
+	}
+	return r.defaultRoute
+}
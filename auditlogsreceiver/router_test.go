@@ -0,0 +1,96 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestRouter(t *testing.T) {
+	cfg := RoutingConfig{
+		Routes: []RouteConfig{
+			{Name: "security", EventTypes: []string{"apiKey*", "userLoggedIn", "rbac*"}},
+			{Name: "platform-operations", EventTypes: []string{"node*", "rebalancing*"}, Labels: []LabelFilter{{Key: "team", Equals: "platform"}}},
+			{Name: "operations", EventTypes: []string{"node*", "rebalancing*"}},
+		},
+		Default: "other",
+	}
+
+	tests := []struct {
+		name string
+		item map[string]interface{}
+		want string
+	}{
+		{
+			name: "when event type matches pattern then its route is used",
+			item: map[string]interface{}{"eventType": "apiKeyCreated"},
+			want: "security",
+		},
+		{
+			name: "when several routes match then the first one is used",
+			item: map[string]interface{}{"eventType": "nodeAdded", "labels": map[string]interface{}{"team": "platform"}},
+			want: "platform-operations",
+		},
+		{
+			name: "when labels do not match then the next route is used",
+			item: map[string]interface{}{"eventType": "nodeAdded", "labels": map[string]interface{}{"team": "data"}},
+			want: "operations",
+		},
+		{
+			name: "when no route matches then the default one is used",
+			item: map[string]interface{}{"eventType": "clusterDeleted"},
+			want: "other",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			router, err := newRouter(cfg)
+			r.NoError(err)
+			r.Equal(tt.want, router.route(tt.item))
+		})
+	}
+
+	t.Run("when no routes are configured then router is disabled", func(t *testing.T) {
+		r := require.New(t)
+
+		router, err := newRouter(RoutingConfig{Default: "default"})
+		r.NoError(err)
+		r.Nil(router)
+		r.Empty(router.route(map[string]interface{}{"eventType": "apiKeyCreated"}))
+	})
+
+	t.Run("when audit logs are processed then route is set as resource attribute", func(t *testing.T) {
+		r := require.New(t)
+
+		router, err := newRouter(cfg)
+		r.NoError(err)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger: zap.L(),
+			router: router,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(time.Now())), &auditLogsMap))
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		route, ok := exported.ResourceLogs().At(0).Resource().Attributes().Get(routeAttribute)
+		r.True(ok)
+		r.Equal("other", route.Str())
+	})
+}
@@ -0,0 +1,192 @@
+package auditlogsreceiver
+
+import (
+	"hash/fnv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/internal/metadata"
+)
+
+const (
+	// samplingRateAttribute holds N of sampling rule keeping one of N items, so downstream counts can be re-weighted.
+	samplingRateAttribute = "castai.sampling.rate"
+	// samplingAnyEventType makes sampling rule match event types not listed by other rules.
+	samplingAnyEventType = "*"
+
+	filterSampling = "sampling"
+
+	// samplingBucketTTL is how long count of a minute is kept after its last item. Audit logs of a minute are mostly
+	// fetched together, yet pages around poll window boundaries bring them out of order.
+	samplingBucketTTL = time.Hour
+)
+
+type samplingRule struct {
+	oneIn        int
+	maxPerMinute int
+}
+
+// samplingBucket identifies a minute of audit log time of an event type.
+type samplingBucket struct {
+	eventType string
+	minute    int64
+}
+
+// minuteCount counts items kept within a minute and remembers when it was last used, so it can be pruned.
+type minuteCount struct {
+	count int
+	used  time.Time
+}
+
+// sampler keeps a sample of audit log items of high-volume event types. It is not safe for concurrent use, items are
+// expected to be sampled while processing lock is held.
+type sampler struct {
+	rules   map[string]samplingRule
+	buckets map[samplingBucket]*minuteCount
+	pruned  time.Time
+	now     func() time.Time
+
+	dropped metric.Int64Counter
+}
+
+func newSampler(cfg []SamplingRule) (*sampler, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	s := &sampler{
+		rules:   map[string]samplingRule{},
+		buckets: map[samplingBucket]*minuteCount{},
+		now:     time.Now,
+	}
+	for _, rule := range cfg {
+		for _, eventType := range rule.EventTypes {
+			s.rules[eventType] = samplingRule{
+				oneIn:        rule.OneIn,
+				maxPerMinute: rule.MaxPerMinute,
+			}
+		}
+	}
+
+	// Counter is no-op until meter provider of the collector is set.
+	err := s.setMeterProvider(noop.NewMeterProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sampler) setMeterProvider(meterProvider metric.MeterProvider) error {
+	if s == nil {
+		return nil
+	}
+
+	var err error
+	s.dropped, err = meterProvider.Meter(metadata.ScopeName).Int64Counter(filterDroppedMetric,
+		metric.WithDescription(filterDroppedDescription),
+		metric.WithUnit("{records}"))
+	return err
+}
+
+// sample tells if the item is kept, together with sampling rate to be attached to its record; rate is 0 when the item
+// is not sampled by one of N rule. Items kept by max per minute rule carry no rate of their own, as the number of audit
+// logs of a minute is not known until all of them are fetched, so they are unweighted. Kept and dropped items are added
+// to counts, and minutes are limited by kept items of counts as well; see commit. It is safe to call on nil sampler,
+// which keeps every item.
+func (s *sampler) sample(item map[string]interface{}, counts *batchCounts) (rate int, keep bool) {
+	if s == nil {
+		return 0, true
+	}
+
+	eventType, _ := item["eventType"].(string)
+	rule, ok := s.rules[eventType]
+	if !ok {
+		rule, ok = s.rules[samplingAnyEventType]
+		if !ok {
+			return 0, true
+		}
+	}
+
+	if rule.oneIn > 1 {
+		// Hashing id keeps the same items when audit logs are polled again or delivered via webhook as well.
+		id, _ := item["id"].(string)
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(id))
+		if h.Sum32()%uint32(rule.oneIn) != 0 {
+			counts.add(s.dropped, attribute.String("filter", filterSampling))
+			return 0, false
+		}
+		rate = rule.oneIn
+	}
+
+	if rule.maxPerMinute > 0 {
+		now := s.now()
+		s.prune(now)
+
+		// Minutes are taken from audit log time, so audit logs fetched in bursts are limited the same as live ones. Every
+		// minute is counted separately, as pages may bring audit logs of a minute out of order.
+		minute := now
+		if str, ok := item["time"].(string); ok {
+			if t, err := time.Parse(timestampLayout, str); err == nil {
+				minute = t
+			}
+		}
+		key := samplingBucket{
+			eventType: eventType,
+			minute:    minute.Truncate(time.Minute).Unix(),
+		}
+
+		count := counts.sampled[key]
+		if bucket, ok := s.buckets[key]; ok {
+			bucket.used = now
+			count += bucket.count
+		}
+		if count >= rule.maxPerMinute {
+			counts.add(s.dropped, attribute.String("filter", filterSampling))
+			return 0, false
+		}
+		if counts.sampled == nil {
+			counts.sampled = map[samplingBucket]int{}
+		}
+		counts.sampled[key]++
+	}
+
+	return rate, true
+}
+
+// commit adds items kept by a consumed batch to counts of their minutes, so items of a batch which was not consumed
+// do not use up limits when they are polled again. It is safe to call on nil sampler.
+func (s *sampler) commit(counts *batchCounts) {
+	if s == nil {
+		return
+	}
+
+	now := s.now()
+	for key, count := range counts.sampled {
+		bucket, ok := s.buckets[key]
+		if !ok {
+			bucket = &minuteCount{}
+			s.buckets[key] = bucket
+		}
+		bucket.count += count
+		bucket.used = now
+	}
+}
+
+// prune forgets counts of minutes which were not used for samplingBucketTTL; it runs at most once a minute.
+func (s *sampler) prune(now time.Time) {
+	if now.Sub(s.pruned) < time.Minute {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.used) > samplingBucketTTL {
+			delete(s.buckets, key)
+		}
+	}
+	s.pruned = now
+}
@@ -0,0 +1,212 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/zap"
+)
+
+func newSamplingItem(eventType string, t time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        uuid.NewString(),
+		"eventType": eventType,
+		"time":      t.UTC().Format(timestampLayout),
+	}
+}
+
+func TestSampler(t *testing.T) {
+	t.Run("when sampling is not configured then sampler is disabled and keeps every item", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler(nil)
+		r.NoError(err)
+		r.Nil(s)
+		r.NoError(s.setMeterProvider(sdkmetric.NewMeterProvider()))
+
+		rate, keep := s.sample(newSamplingItem("nodeAdded", time.Now()), &batchCounts{})
+		r.True(keep)
+		r.Zero(rate)
+		s.commit(&batchCounts{})
+	})
+
+	t.Run("when one in N is configured then decision is deterministic by id and rate is N", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{"nodeAdded"}, OneIn: 10}})
+		r.NoError(err)
+
+		kept := 0
+		for i := 0; i < 1000; i++ {
+			item := newSamplingItem("nodeAdded", time.Now())
+			rate, keep := s.sample(item, &batchCounts{})
+			if keep {
+				kept++
+				r.Equal(10, rate)
+			}
+
+			// The same item is always sampled the same way.
+			_, again := s.sample(item, &batchCounts{})
+			r.Equal(keep, again)
+		}
+		r.InDelta(100, kept, 50)
+
+		rate, keep := s.sample(newSamplingItem("clusterDeleted", time.Now()), &batchCounts{})
+		r.True(keep)
+		r.Zero(rate)
+	})
+
+	t.Run("when max per minute is configured then items over limit of a minute are dropped", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{samplingAnyEventType}, MaxPerMinute: 2}})
+		r.NoError(err)
+
+		reader := sdkmetric.NewManualReader()
+		r.NoError(s.setMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+		counts := &batchCounts{}
+		minute := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+		var kept []bool
+		for _, item := range []map[string]interface{}{
+			newSamplingItem("nodeAdded", minute),
+			newSamplingItem("nodeAdded", minute.Add(10*time.Second)),
+			newSamplingItem("nodeAdded", minute.Add(20*time.Second)),
+			newSamplingItem("nodeRemoved", minute.Add(30*time.Second)),
+			newSamplingItem("nodeAdded", minute.Add(time.Minute)),
+		} {
+			rate, keep := s.sample(item, counts)
+			r.Zero(rate)
+			kept = append(kept, keep)
+		}
+		r.Equal([]bool{true, true, false, true, true}, kept)
+
+		// Drops are counted only once the batch is consumed.
+		r.Zero(collectSum(t, reader, filterDroppedMetric))
+		counts.record(context.Background())
+		r.Equal(int64(1), collectSum(t, reader, filterDroppedMetric))
+	})
+
+	t.Run("when audit logs of a minute come out of order then they are limited together", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{"nodeAdded"}, MaxPerMinute: 2}})
+		r.NoError(err)
+
+		// Pages bring the newer minute, then the older one, then the newer one again.
+		minute := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+		var kept []bool
+		counts := &batchCounts{}
+		for _, tm := range []time.Time{
+			minute.Add(time.Minute),
+			minute.Add(time.Minute + time.Second),
+			minute,
+			minute.Add(time.Second),
+			minute.Add(time.Minute + 2*time.Second),
+			minute.Add(2 * time.Second),
+		} {
+			_, keep := s.sample(newSamplingItem("nodeAdded", tm), counts)
+			kept = append(kept, keep)
+			// Every page is a batch of its own.
+			s.commit(counts)
+			counts = &batchCounts{}
+		}
+		r.Equal([]bool{true, true, true, true, false, false}, kept)
+	})
+
+	t.Run("when count of a minute was not used for a while then it is pruned", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{"nodeAdded"}, MaxPerMinute: 1}})
+		r.NoError(err)
+		now := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+
+		minute := now.Add(-24 * time.Hour)
+		counts := &batchCounts{}
+		_, keep := s.sample(newSamplingItem("nodeAdded", minute), counts)
+		r.True(keep)
+		_, keep = s.sample(newSamplingItem("nodeAdded", minute.Add(time.Minute)), counts)
+		r.True(keep)
+		s.commit(counts)
+		r.Len(s.buckets, 2)
+
+		now = now.Add(samplingBucketTTL + time.Minute)
+		_, keep = s.sample(newSamplingItem("nodeAdded", minute.Add(time.Minute)), &batchCounts{})
+		r.True(keep)
+		r.Empty(s.buckets)
+	})
+
+	t.Run("when batch is not consumed then its items do not use up the limit", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{"nodeAdded"}, MaxPerMinute: 2}})
+		r.NoError(err)
+
+		minute := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+		sampleBatch := func() []bool {
+			counts := &batchCounts{}
+			var kept []bool
+			for i := 0; i < 3; i++ {
+				_, keep := s.sample(newSamplingItem("nodeAdded", minute.Add(time.Duration(i)*time.Second)), counts)
+				kept = append(kept, keep)
+			}
+			return kept
+		}
+
+		// The first attempt is not committed, so polling the batch again makes the same decisions.
+		r.Equal([]bool{true, true, false}, sampleBatch())
+		r.Equal([]bool{true, true, false}, sampleBatch())
+	})
+
+	t.Run("when audit logs are processed then kept records carry sampling rate", func(t *testing.T) {
+		r := require.New(t)
+
+		s, err := newSampler([]SamplingRule{{EventTypes: []string{"clusterDeleted"}, OneIn: 2}})
+		r.NoError(err)
+
+		var exported plog.Logs
+		receiver := auditLogsReceiver{
+			logger:  zap.L(),
+			sampler: s,
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					exported = logs
+					return nil
+				},
+			},
+		}
+
+		var items []interface{}
+		for i := 0; i < 20; i++ {
+			items = append(items, map[string]interface{}{
+				"id":        fmt.Sprintf("id-%d", i),
+				"eventType": "clusterDeleted",
+				"time":      time.Now().UTC().Format(timestampLayout),
+			})
+		}
+		body, err := json.Marshal(map[string]interface{}{"items": items})
+		r.NoError(err)
+
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal(body, &auditLogsMap))
+		_, err = receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+
+		r.Greater(exported.LogRecordCount(), 0)
+		r.Less(exported.LogRecordCount(), 20)
+		records := exported.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+		for i := 0; i < records.Len(); i++ {
+			rate, ok := records.At(i).Attributes().Get(samplingRateAttribute)
+			r.True(ok)
+			r.Equal(int64(2), rate.Int())
+		}
+	})
+}
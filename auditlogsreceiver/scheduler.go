@@ -0,0 +1,51 @@
+package auditlogsreceiver
+
+import (
+	"time"
+)
+
+// pollStats describes the outcome of a single poll cycle.
+type pollStats struct {
+	// records is the number of audit logs returned by the API.
+	records int
+	// limitReached is set when any page had page limit items or a batch was consumed before the last page, so the poll
+	// worked through a backlog and more audit logs are likely pending.
+	limitReached bool
+}
+
+// pollScheduler decides how long to wait before the next poll cycle. With adaptive polling disabled, interval is
+// fixed; otherwise it is halved (down to min) when audit logs are flowing and doubled (up to max) when idle.
+type pollScheduler struct {
+	interval    time.Duration
+	minInterval time.Duration
+	maxInterval time.Duration
+	adaptive    bool
+}
+
+func newPollScheduler(interval time.Duration, cfg AdaptivePollingConfig) *pollScheduler {
+	return &pollScheduler{
+		interval:    interval,
+		minInterval: cfg.MinInterval,
+		maxInterval: cfg.MaxInterval,
+		adaptive:    cfg.Enabled,
+	}
+}
+
+func (s *pollScheduler) next(stats pollStats, err error) time.Duration {
+	// Failed poll keeps the current pace, so errors neither hammer the API nor delay recovery.
+	if !s.adaptive || err != nil {
+		return s.interval
+	}
+
+	switch {
+	case stats.limitReached:
+		// Page or batch limit was hit, so there is likely more to fetch right away.
+		return 0
+	case stats.records > 0:
+		s.interval = max(s.interval/2, s.minInterval)
+	default:
+		s.interval = min(s.interval*2, s.maxInterval)
+	}
+
+	return s.interval
+}
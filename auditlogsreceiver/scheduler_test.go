@@ -0,0 +1,47 @@
+package auditlogsreceiver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollScheduler(t *testing.T) {
+	t.Run("when adaptive polling is disabled then interval is fixed", func(t *testing.T) {
+		r := require.New(t)
+
+		s := newPollScheduler(10*time.Second, AdaptivePollingConfig{})
+		r.Equal(10*time.Second, s.next(pollStats{records: 100, limitReached: true}, nil))
+		r.Equal(10*time.Second, s.next(pollStats{}, nil))
+	})
+
+	t.Run("when adaptive polling is enabled then interval follows activity within bounds", func(t *testing.T) {
+		r := require.New(t)
+
+		s := newPollScheduler(10*time.Second, AdaptivePollingConfig{
+			Enabled:     true,
+			MinInterval: 3 * time.Second,
+			MaxInterval: 30 * time.Second,
+		})
+
+		// Audit logs are flowing, so interval shrinks down to min.
+		r.Equal(5*time.Second, s.next(pollStats{records: 5}, nil))
+		r.Equal(3*time.Second, s.next(pollStats{records: 5}, nil))
+		r.Equal(3*time.Second, s.next(pollStats{records: 5}, nil))
+
+		// Page or batch limit was hit, so the next poll is immediate without changing the pace.
+		r.Zero(s.next(pollStats{records: 100, limitReached: true}, nil))
+
+		// Errors keep the pace.
+		r.Equal(3*time.Second, s.next(pollStats{}, errors.New("failed")))
+
+		// Idle polls back off up to max.
+		r.Equal(6*time.Second, s.next(pollStats{}, nil))
+		r.Equal(12*time.Second, s.next(pollStats{}, nil))
+		r.Equal(24*time.Second, s.next(pollStats{}, nil))
+		r.Equal(30*time.Second, s.next(pollStats{}, nil))
+		r.Equal(30*time.Second, s.next(pollStats{}, nil))
+	})
+}
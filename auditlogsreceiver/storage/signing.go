@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// IntegrityPolicy defines how persistent storage reacts to poll data whose signature cannot be verified.
+type IntegrityPolicy string
+
+const (
+	// IntegrityPolicyRefuse fails storage creation, so receiver does not start until the file is fixed by an operator.
+	IntegrityPolicyRefuse IntegrityPolicy = "refuse"
+	// IntegrityPolicyReset discards poll data and starts from the current time, as if the file did not exist.
+	IntegrityPolicyReset IntegrityPolicy = "reset"
+)
+
+var ErrIntegrity = errors.New("poll data signature verification failed")
+
+type PersistentStorageOption func(*persistentStorage)
+
+// WithSigning makes persistent storage sign poll data with HMAC-SHA256 using the key and verify the signature on load.
+func WithSigning(key []byte, policy IntegrityPolicy) PersistentStorageOption {
+	return func(s *persistentStorage) {
+		s.signingKey = key
+		s.integrityPolicy = policy
+	}
+}
+
+// signedPollData is a format of poll data file; signature is omitted when signing is not enabled.
+type signedPollData struct {
+	PollData
+	Signature string `json:"signature,omitempty"`
+}
+
+func (s *persistentStorage) sign(data PollData) (string, error) {
+	jsonBytes, err := json.Marshal(&data)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write(jsonBytes)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *persistentStorage) verify(data signedPollData) error {
+	if data.Signature == "" {
+		return fmt.Errorf("%w: signature is missing", ErrIntegrity)
+	}
+
+	expected, err := s.sign(data.PollData)
+	if err != nil {
+		return err
+	}
+
+	actual, err := hex.DecodeString(data.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrIntegrity)
+	}
+
+	expectedBytes, _ := hex.DecodeString(expected)
+	if !hmac.Equal(expectedBytes, actual) {
+		return fmt.Errorf("%w: signature does not match", ErrIntegrity)
+	}
+
+	return nil
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 	"io"
+	"maps"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -15,25 +18,53 @@ type PollData struct {
 	CheckPoint     time.Time  `json:"check_point"`
 	NextCheckPoint *time.Time `json:"next_check_point,omitempty"`
 	ToDate         *time.Time `json:"to_date,omitempty"`
+	// Chain is the head of hash chain computed over exported audit logs; present only when hash chain is enabled.
+	Chain *ChainHead `json:"chain,omitempty"`
+	// Chains are heads of hash chains of every route; present instead of Chain when routing is configured.
+	Chains map[string]ChainHead `json:"chains,omitempty"`
 }
 
+type ChainHead struct {
+	Sequence uint64 `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// Storage keeps poll data; implementations are safe for concurrent use, and poll data is copied in and out, so
+// callers never share it with the storage.
 type Storage interface {
 	Get() PollData
 	Save(PollData) error
 }
 
+// clone returns a deep copy of poll data, so pointer fields are not shared between copies.
+func (p PollData) clone() PollData {
+	if p.NextCheckPoint != nil {
+		p.NextCheckPoint = lo.ToPtr(*p.NextCheckPoint)
+	}
+	if p.ToDate != nil {
+		p.ToDate = lo.ToPtr(*p.ToDate)
+	}
+	if p.Chain != nil {
+		p.Chain = lo.ToPtr(*p.Chain)
+	}
+	p.Chains = maps.Clone(p.Chains)
+	return p
+}
+
 type inMemoryStorage struct {
-	logger   *zap.Logger
+	logger *zap.Logger
+
+	mu       sync.RWMutex
 	pollData PollData
 }
 
-func NewInMemoryStorage(logger *zap.Logger, backFromNowSec int) Storage {
-	logger.Info("new in-memory storage was created", zap.Int("back_from_now_sec", backFromNowSec))
+func NewInMemoryStorage(logger *zap.Logger, lookback time.Duration) Storage {
+	logger.Info("new in-memory storage was created", zap.Duration("lookback", lookback))
 
 	return &inMemoryStorage{
 		logger: logger,
 		pollData: PollData{
-			CheckPoint:     time.Now().Add(-time.Second * time.Duration(backFromNowSec)),
+			CheckPoint:     time.Now().Add(-lookback),
 			NextCheckPoint: nil,
 			ToDate:         nil,
 		},
@@ -41,36 +72,45 @@ func NewInMemoryStorage(logger *zap.Logger, backFromNowSec int) Storage {
 }
 
 func (s *inMemoryStorage) Get() PollData {
-	return s.pollData
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pollData.clone()
 }
 
 func (s *inMemoryStorage) Save(data PollData) error {
-	s.pollData = data
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pollData = data.clone()
 	return nil
 }
 
 type persistentStorage struct {
 	filename string
 	inMemoryStorage
+
+	signingKey      []byte
+	integrityPolicy IntegrityPolicy
 }
 
-func NewPersistentStorage(logger *zap.Logger, filename string) (Storage, error) {
+func NewPersistentStorage(logger *zap.Logger, filename string, opts ...PersistentStorageOption) (Storage, error) {
 	storage := persistentStorage{
 		// TODO: consider using NewInMemoryStorage(..), then no need for creating PollData when creating a file.
 		inMemoryStorage: inMemoryStorage{
 			logger: logger,
 		},
-		filename: filename,
+		filename:        filename,
+		integrityPolicy: IntegrityPolicyRefuse,
+	}
+	for _, opt := range opts {
+		opt(&storage)
 	}
 
 	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
-		err = storage.Save(PollData{
-			CheckPoint:     time.Now(),
-			NextCheckPoint: nil,
-			ToDate:         nil,
-		})
+		err = storage.reset()
 		if err != nil {
-			return nil, fmt.Errorf("saving poll data configuration file: %w", err)
+			return nil, err
 		}
 		logger.Info("new persistent storage was created", zap.Any("filename", storage.filename), zap.Any("poll_data", storage.inMemoryStorage.pollData))
 
@@ -88,11 +128,30 @@ func NewPersistentStorage(logger *zap.Logger, filename string) (Storage, error)
 		return nil, fmt.Errorf("reading poll data configuration file: %w", err)
 	}
 
-	err = json.Unmarshal(byteValue, &storage.inMemoryStorage.pollData)
+	var data signedPollData
+	err = json.Unmarshal(byteValue, &data)
 	if err != nil {
 		return nil, fmt.Errorf("parsing poll data configuration file: %w", err)
 	}
 
+	if storage.signingKey != nil {
+		err = storage.verify(data)
+		if err != nil {
+			if storage.integrityPolicy != IntegrityPolicyReset {
+				return nil, fmt.Errorf("verifying poll data configuration file: %w", err)
+			}
+
+			logger.Warn("poll data configuration file failed integrity verification, resetting", zap.Any("filename", storage.filename), zap.Error(err))
+			err = storage.reset()
+			if err != nil {
+				return nil, err
+			}
+
+			return &storage, nil
+		}
+	}
+	storage.inMemoryStorage.pollData = data.PollData
+
 	// Format validation is done by JSON unmarshaller, so here it is only 'semantic' validations.
 	err = storage.validate()
 	if err != nil {
@@ -105,13 +164,28 @@ func NewPersistentStorage(logger *zap.Logger, filename string) (Storage, error)
 }
 
 func (s *persistentStorage) Get() PollData {
-	return s.pollData
+	return s.inMemoryStorage.Get()
 }
 
 func (s *persistentStorage) Save(data PollData) error {
-	s.pollData = data
+	// Lock is held while the file is written, so concurrent saves cannot leave the file older than poll data in memory.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pollData = data.clone()
+
+	file := signedPollData{
+		PollData: s.pollData,
+	}
+	if s.signingKey != nil {
+		signature, err := s.sign(file.PollData)
+		if err != nil {
+			return err
+		}
+		file.Signature = signature
+	}
 
-	jsonBytes, err := json.Marshal(&s.inMemoryStorage.pollData)
+	jsonBytes, err := json.Marshal(&file)
 	if err != nil {
 		return err
 	}
@@ -124,20 +198,39 @@ func (s *persistentStorage) Save(data PollData) error {
 	return nil
 }
 
+// reset stores fresh poll data starting from the current time.
+func (s *persistentStorage) reset() error {
+	err := s.Save(PollData{
+		CheckPoint:     time.Now(),
+		NextCheckPoint: nil,
+		ToDate:         nil,
+	})
+	if err != nil {
+		return fmt.Errorf("saving poll data configuration file: %w", err)
+	}
+
+	return nil
+}
+
 func (s *persistentStorage) validate() error {
-	if s.inMemoryStorage.pollData.NextCheckPoint != nil {
-		if s.inMemoryStorage.pollData.ToDate == nil {
+	return s.inMemoryStorage.pollData.Validate()
+}
+
+// Validate checks that poll data dates are consistent with each other.
+func (p PollData) Validate() error {
+	if p.NextCheckPoint != nil {
+		if p.ToDate == nil {
 			return fmt.Errorf("to_date must be provided when next_check_point date is present")
 		}
 
-		if s.inMemoryStorage.pollData.NextCheckPoint.Before(s.inMemoryStorage.pollData.CheckPoint) {
+		if p.NextCheckPoint.Before(p.CheckPoint) {
 			return fmt.Errorf("next_check_point date must succeed check_point")
 		}
-		if s.inMemoryStorage.pollData.ToDate.Before(s.inMemoryStorage.pollData.CheckPoint) {
+		if p.ToDate.Before(p.CheckPoint) {
 			return fmt.Errorf("to_date date must succeed check_point")
 		}
 
-		if s.inMemoryStorage.pollData.NextCheckPoint.Before(*s.inMemoryStorage.pollData.ToDate) {
+		if p.NextCheckPoint.Before(*p.ToDate) {
 			return fmt.Errorf("next_check_point date must succeed or be equal to to_date")
 		}
 	}
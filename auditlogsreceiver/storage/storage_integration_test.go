@@ -3,6 +3,7 @@ package storage
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -61,3 +62,122 @@ func TestPersistentStorage(t *testing.T) {
 		defer jsonFile.Close()
 	})
 }
+
+func TestPersistentStorageConcurrency(t *testing.T) {
+	t.Run("when Get and Save are called concurrently then poll data in memory and in the file is consistent", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := filepath.Join(t.TempDir(), "poll_data.json")
+		s, err := NewPersistentStorage(zap.L(), filename, WithSigning([]byte("secret"), IntegrityPolicyRefuse))
+		r.NoError(err)
+
+		hammerStorage(t, s)
+
+		// The file must be written by the last Save, so reloading it gives the same poll data.
+		reloaded, err := NewPersistentStorage(zap.L(), filename, WithSigning([]byte("secret"), IntegrityPolicyRefuse))
+		r.NoError(err)
+		r.Equal(s.Get().Chain, reloaded.Get().Chain)
+		r.WithinDuration(*s.Get().ToDate, *reloaded.Get().ToDate, 0)
+	})
+}
+
+func TestSignedPersistentStorage(t *testing.T) {
+	logger := zap.L()
+	key := []byte("secret")
+
+	newSignedFile := func(t *testing.T) (string, PollData) {
+		t.Helper()
+		r := require.New(t)
+
+		filename := filepath.Join(t.TempDir(), "poll_data.json")
+		s, err := NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyRefuse))
+		r.NoError(err)
+
+		p := PollData{
+			CheckPoint:     time.Now().Add(-time.Hour),
+			NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
+			ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
+		}
+		r.NoError(s.Save(p))
+
+		return filename, p
+	}
+
+	tamper := func(t *testing.T, filename string) {
+		t.Helper()
+		r := require.New(t)
+
+		jsonBytes, err := os.ReadFile(filename)
+		r.NoError(err)
+		var data map[string]interface{}
+		r.NoError(json.Unmarshal(jsonBytes, &data))
+
+		data["check_point"] = time.Now().Add(-time.Minute).Format(time.RFC3339Nano)
+		jsonBytes, err = json.Marshal(data)
+		r.NoError(err)
+		r.NoError(os.WriteFile(filename, jsonBytes, os.ModePerm))
+	}
+
+	t.Run("when signed file is loaded with the same key then Get provides correct data", func(t *testing.T) {
+		r := require.New(t)
+
+		filename, p := newSignedFile(t)
+
+		s, err := NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyRefuse))
+		r.NoError(err)
+		r.WithinDuration(p.CheckPoint, s.Get().CheckPoint, 0)
+		r.WithinDuration(*p.ToDate, *s.Get().ToDate, 0)
+		r.WithinDuration(*p.NextCheckPoint, *s.Get().NextCheckPoint, 0)
+	})
+
+	t.Run("when signed file is tampered and policy is refuse then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		filename, _ := newSignedFile(t)
+		tamper(t, filename)
+
+		_, err := NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyRefuse))
+		r.ErrorIs(err, ErrIntegrity)
+
+		_, err = NewPersistentStorage(logger, filename, WithSigning([]byte("another secret"), IntegrityPolicyRefuse))
+		r.ErrorIs(err, ErrIntegrity)
+	})
+
+	t.Run("when signed file is tampered and policy is reset then poll data starts from now", func(t *testing.T) {
+		r := require.New(t)
+
+		filename, _ := newSignedFile(t)
+		tamper(t, filename)
+
+		s, err := NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyReset))
+		r.NoError(err)
+		r.WithinDuration(time.Now(), s.Get().CheckPoint, time.Second)
+		r.Nil(s.Get().ToDate)
+		r.Nil(s.Get().NextCheckPoint)
+
+		// Reset file is signed again.
+		_, err = NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyRefuse))
+		r.NoError(err)
+	})
+
+	t.Run("when unsigned file is loaded with signing enabled then an error is returned", func(t *testing.T) {
+		r := require.New(t)
+
+		filename := filepath.Join(t.TempDir(), "poll_data.json")
+		_, err := NewPersistentStorage(logger, filename)
+		r.NoError(err)
+
+		_, err = NewPersistentStorage(logger, filename, WithSigning(key, IntegrityPolicyRefuse))
+		r.ErrorIs(err, ErrIntegrity)
+	})
+
+	t.Run("when signed file is loaded without signing then signature is ignored", func(t *testing.T) {
+		r := require.New(t)
+
+		filename, p := newSignedFile(t)
+
+		s, err := NewPersistentStorage(logger, filename)
+		r.NoError(err)
+		r.WithinDuration(p.CheckPoint, s.Get().CheckPoint, 0)
+	})
+}
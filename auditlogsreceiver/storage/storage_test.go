@@ -4,6 +4,7 @@ import (
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"sync"
 	"testing"
 	"time"
 )
@@ -14,18 +15,18 @@ func TestInMemoryStorage(t *testing.T) {
 	t.Run("when new poll data is created by a constructor then Get provides correct data", func(t *testing.T) {
 		r := require.New(t)
 
-		backFromNow := 99
-		s := NewInMemoryStorage(logger, backFromNow)
+		lookback := 99 * time.Second
+		s := NewInMemoryStorage(logger, lookback)
 
 		p := s.Get()
 		r.True(p.CheckPoint.Before(time.Now()))
-		r.True(p.CheckPoint.After(time.Now().Add(-time.Second*time.Duration(backFromNow) - 100*time.Millisecond)))
+		r.True(p.CheckPoint.After(time.Now().Add(-lookback - 100*time.Millisecond)))
 	})
 
 	t.Run("when new poll data is set by calling Save method then Get provides correct data", func(t *testing.T) {
 		r := require.New(t)
 
-		s := NewInMemoryStorage(logger, 1)
+		s := NewInMemoryStorage(logger, time.Second)
 		p := PollData{
 			CheckPoint:     time.Now(),
 			NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
@@ -61,10 +62,77 @@ func TestInMemoryStorage(t *testing.T) {
 	})
 }
 
+func TestInMemoryStorageConcurrency(t *testing.T) {
+	t.Run("when poll data returned by Get is modified then stored poll data is not affected", func(t *testing.T) {
+		r := require.New(t)
+
+		s := NewInMemoryStorage(zap.L(), 0)
+		toDate := time.Now()
+		r.NoError(s.Save(PollData{
+			CheckPoint:     toDate.Add(-time.Minute),
+			NextCheckPoint: lo.ToPtr(toDate),
+			ToDate:         lo.ToPtr(toDate),
+			Chain:          &ChainHead{Sequence: 1, Hash: "hash"},
+			Chains:         map[string]ChainHead{"security": {Sequence: 1, Hash: "hash"}},
+		}))
+
+		p := s.Get()
+		*p.ToDate = toDate.Add(time.Hour)
+		p.Chain.Sequence = 2
+		p.Chains["security"] = ChainHead{Sequence: 2}
+
+		r.Equal(toDate, *s.Get().ToDate)
+		r.Equal(uint64(1), s.Get().Chain.Sequence)
+		r.Equal(uint64(1), s.Get().Chains["security"].Sequence)
+	})
+
+	t.Run("when Get and Save are called concurrently then poll data is consistent", func(t *testing.T) {
+		s := NewInMemoryStorage(zap.L(), 0)
+		hammerStorage(t, s)
+	})
+}
+
+// hammerStorage calls Get and Save from many goroutines; it is meant to be run with race detector.
+func hammerStorage(t *testing.T, s Storage) {
+	t.Helper()
+	r := require.New(t)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				p := s.Get()
+				next := p.CheckPoint.Add(time.Second)
+				p.CheckPoint = start
+				p.NextCheckPoint = lo.ToPtr(next)
+				p.ToDate = lo.ToPtr(next)
+				if p.Chain == nil {
+					p.Chain = &ChainHead{}
+				}
+				p.Chain.Sequence++
+				if err := s.Save(p); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	p := s.Get()
+	r.NoError(p.Validate())
+	r.Equal(start, p.CheckPoint)
+	r.NotNil(p.Chain)
+}
+
 func TestPersistentStorageValidate(t *testing.T) {
 	type fields struct {
-		filename        string
-		inMemoryStorage inMemoryStorage
+		filename string
+		pollData PollData
 	}
 	tests := []struct {
 		name    string
@@ -74,10 +142,8 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "correct data without next_check_point and to_date",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint: time.Now(),
-					},
+				pollData: PollData{
+					CheckPoint: time.Now(),
 				},
 			},
 			wantErr: false,
@@ -85,12 +151,10 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "correct data with next_check_point and to_date",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint:     time.Now(),
-						NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
-						ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
-					},
+				pollData: PollData{
+					CheckPoint:     time.Now(),
+					NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
+					ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
 				},
 			},
 			wantErr: false,
@@ -98,11 +162,9 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "incorrect data: to_date is missing when next_check_point is provided",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint:     time.Now(),
-						NextCheckPoint: lo.ToPtr(time.Now().Add(1 * time.Second)),
-					},
+				pollData: PollData{
+					CheckPoint:     time.Now(),
+					NextCheckPoint: lo.ToPtr(time.Now().Add(1 * time.Second)),
 				},
 			},
 			wantErr: true,
@@ -110,12 +172,10 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "incorrect data: check_point is beyond next_check_point",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint:     time.Now().Add(3 * time.Second),
-						NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
-						ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
-					},
+				pollData: PollData{
+					CheckPoint:     time.Now().Add(3 * time.Second),
+					NextCheckPoint: lo.ToPtr(time.Now().Add(2 * time.Second)),
+					ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
 				},
 			},
 			wantErr: true,
@@ -123,12 +183,10 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "incorrect data: check_point is beyond to_date",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint:     time.Now().Add(2 * time.Second),
-						NextCheckPoint: lo.ToPtr(time.Now().Add(3 * time.Second)),
-						ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
-					},
+				pollData: PollData{
+					CheckPoint:     time.Now().Add(2 * time.Second),
+					NextCheckPoint: lo.ToPtr(time.Now().Add(3 * time.Second)),
+					ToDate:         lo.ToPtr(time.Now().Add(1 * time.Second)),
 				},
 			},
 			wantErr: true,
@@ -136,12 +194,10 @@ func TestPersistentStorageValidate(t *testing.T) {
 		{
 			name: "incorrect data: to_date is beyond next_check_point",
 			fields: fields{
-				inMemoryStorage: inMemoryStorage{
-					pollData: PollData{
-						CheckPoint:     time.Now(),
-						NextCheckPoint: lo.ToPtr(time.Now().Add(1 * time.Second)),
-						ToDate:         lo.ToPtr(time.Now().Add(2 * time.Second)),
-					},
+				pollData: PollData{
+					CheckPoint:     time.Now(),
+					NextCheckPoint: lo.ToPtr(time.Now().Add(1 * time.Second)),
+					ToDate:         lo.ToPtr(time.Now().Add(2 * time.Second)),
 				},
 			},
 			wantErr: true,
@@ -150,8 +206,10 @@ func TestPersistentStorageValidate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &persistentStorage{
-				filename:        tt.fields.filename,
-				inMemoryStorage: tt.fields.inMemoryStorage,
+				filename: tt.fields.filename,
+				inMemoryStorage: inMemoryStorage{
+					pollData: tt.fields.pollData,
+				},
 			}
 			if err := s.validate(); (err != nil) != tt.wantErr {
 				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
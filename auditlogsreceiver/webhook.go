@@ -0,0 +1,164 @@
+package auditlogsreceiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookPath = "/audit-logs"
+
+	// webhookSignatureHeader carries hex encoded HMAC-SHA256 of the request body, optionally prefixed with "sha256=".
+	webhookSignatureHeader = "X-Castai-Signature"
+
+	// deliveredIDsCapacity limits how many recently delivered audit log IDs are remembered for de-duplication.
+	deliveredIDsCapacity = 10000
+)
+
+// webhookHandler accepts audit logs pushed by CAST AI and converts them the same way as polled ones.
+type webhookHandler struct {
+	logger  *zap.Logger
+	path    string
+	secret  []byte
+	process func(ctx context.Context, auditLogsMap map[string]interface{}) error
+}
+
+func readWebhookSecret(filename string) ([]byte, error) {
+	secret, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading webhook secret file: %w", err)
+	}
+
+	// Trailing new lines are common in files created by hand or mounted from secrets, so they are not part of the secret.
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, errors.New("webhook secret file is empty")
+	}
+
+	return secret, nil
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != h.path {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verify(req.Header.Get(webhookSignatureHeader), body) {
+		h.logger.Warn("audit logs webhook request with invalid signature was rejected", zap.String("remote_addr", req.RemoteAddr))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	auditLogsMap, err := parseWebhookPayload(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.process(req.Context(), auditLogsMap)
+	if err != nil {
+		// Sender is expected to retry; even if it does not, polling delivers missed audit logs.
+		h.logger.Error("processing audit logs webhook request", zap.Error(err))
+		http.Error(w, "processing audit logs", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *webhookHandler) verify(signature string, body []byte) bool {
+	actual, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(actual) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), actual)
+}
+
+// parseWebhookPayload accepts either a page of audit logs (the same as returned by the API) or a single audit log,
+// and returns it in the form of a page.
+func parseWebhookPayload(body []byte) (map[string]interface{}, error) {
+	var payload map[string]interface{}
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return nil, errors.New("payload must be a JSON object")
+	}
+
+	if _, ok := payload["items"]; ok {
+		return payload, nil
+	}
+	if _, ok := payload["id"]; ok {
+		return map[string]interface{}{
+			"items": []interface{}{payload},
+		}, nil
+	}
+
+	return nil, errors.New("payload must contain an audit log or a list of items")
+}
+
+// deliveredIDs remembers IDs of recently delivered audit logs, so the same audit log received both via webhook and
+// polling is delivered only once. It is not goroutine-safe, callers are expected to serialize access.
+type deliveredIDs struct {
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newDeliveredIDs(capacity int) *deliveredIDs {
+	return &deliveredIDs{
+		ids:   make(map[string]struct{}, capacity),
+		order: make([]string, 0, capacity),
+	}
+}
+
+// contains is safe to call on nil deliveredIDs, which is the case when de-duplication is not needed.
+func (d *deliveredIDs) contains(id string) bool {
+	if d == nil {
+		return false
+	}
+
+	_, ok := d.ids[id]
+	return ok
+}
+
+func (d *deliveredIDs) add(id string) {
+	if d == nil || id == "" || d.contains(id) {
+		return
+	}
+
+	// Oldest ID is evicted once capacity is reached.
+	if len(d.order) < cap(d.order) {
+		d.order = append(d.order, id)
+	} else {
+		delete(d.ids, d.order[d.next])
+		d.order[d.next] = id
+		d.next = (d.next + 1) % len(d.order)
+	}
+	d.ids[id] = struct{}{}
+}
@@ -0,0 +1,213 @@
+package auditlogsreceiver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"github.com/castai/audit-logs-receiver/audit-logs/storage"
+)
+
+func sign(secret []byte, body string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookRequest(body, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, defaultWebhookPath, strings.NewReader(body))
+	if signature != "" {
+		req.Header.Set(webhookSignatureHeader, signature)
+	}
+	return req
+}
+
+func TestWebhookHandler(t *testing.T) {
+	secret := []byte("secret")
+	lastLogTimestamp := time.Now().Add(-time.Minute)
+
+	newHandler := func(consumed *int) (*webhookHandler, *auditLogsReceiver) {
+		receiver := &auditLogsReceiver{
+			logger:    zap.L(),
+			delivered: newDeliveredIDs(deliveredIDsCapacity),
+			consumer: logsConsumerMock{
+				ConsumeLogsFunc: func(logs plog.Logs) error {
+					*consumed += logs.LogRecordCount()
+					return nil
+				},
+			},
+		}
+
+		return &webhookHandler{
+			logger: zap.L(),
+			path:   defaultWebhookPath,
+			secret: secret,
+			process: func(ctx context.Context, auditLogsMap map[string]interface{}) error {
+				_, err := receiver.processAuditLogs(ctx, auditLogsMap)
+				return err
+			},
+		}, receiver
+	}
+
+	t.Run("when request is signed correctly then audit logs are consumed", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		handler, _ := newHandler(&consumed)
+
+		body := newResponseWithOneItem(lastLogTimestamp)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newWebhookRequest(body, sign(secret, body)))
+		r.Equal(http.StatusOK, rec.Code)
+		r.Equal(1, consumed)
+	})
+
+	t.Run("when single audit log is pushed then it is consumed", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		handler, _ := newHandler(&consumed)
+
+		var page map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(newResponseWithOneItem(lastLogTimestamp)), &page))
+		item, err := json.Marshal(page["items"].([]interface{})[0])
+		r.NoError(err)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newWebhookRequest(string(item), sign(secret, string(item))))
+		r.Equal(http.StatusOK, rec.Code)
+		r.Equal(1, consumed)
+	})
+
+	t.Run("when signature is missing or invalid then request is rejected", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		handler, _ := newHandler(&consumed)
+
+		body := newResponseWithOneItem(lastLogTimestamp)
+		for _, signature := range []string{"", "sha256=00", sign([]byte("another secret"), body), "not hex"} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newWebhookRequest(body, signature))
+			r.Equal(http.StatusUnauthorized, rec.Code)
+		}
+		r.Zero(consumed)
+	})
+
+	t.Run("when payload is invalid or request is not a POST then request is rejected", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		handler, _ := newHandler(&consumed)
+
+		for _, body := range []string{`[]`, `{"nothing": true}`} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newWebhookRequest(body, sign(secret, body)))
+			r.Equal(http.StatusBadRequest, rec.Code)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultWebhookPath, nil))
+		r.Equal(http.StatusMethodNotAllowed, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/other", nil))
+		r.Equal(http.StatusNotFound, rec.Code)
+		r.Zero(consumed)
+	})
+
+	t.Run("when audit logs delivered via webhook are polled then they are not consumed again", func(t *testing.T) {
+		r := require.New(t)
+
+		consumed := 0
+		handler, receiver := newHandler(&consumed)
+
+		body := newResponseWithOneItem(lastLogTimestamp)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newWebhookRequest(body, sign(secret, body)))
+		r.Equal(http.StatusOK, rec.Code)
+
+		// The same audit log is returned by polling, which must not deliver it again but still move the position.
+		var auditLogsMap map[string]interface{}
+		r.NoError(json.Unmarshal([]byte(body), &auditLogsMap))
+		lastTimestamp, err := receiver.processAuditLogs(context.Background(), auditLogsMap)
+		r.NoError(err)
+		r.NotNil(lastTimestamp)
+		r.WithinDuration(lastLogTimestamp, *lastTimestamp, time.Microsecond)
+		r.Equal(1, consumed)
+	})
+}
+
+func TestDeliveredIDs(t *testing.T) {
+	r := require.New(t)
+
+	var disabled *deliveredIDs
+	disabled.add("a")
+	r.False(disabled.contains("a"))
+
+	d := newDeliveredIDs(3)
+	for i := 0; i < 5; i++ {
+		d.add(strconv.Itoa(i))
+	}
+	d.add("4")
+
+	r.False(d.contains("0"))
+	r.False(d.contains("1"))
+	for _, id := range []string{"2", "3", "4"} {
+		r.True(d.contains(id))
+	}
+	r.Len(d.ids, 3)
+}
+
+func TestWebhookStartShutdown(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	restConfig := Config{
+		API: API{
+			Url: "https://api.cast.ai",
+			Key: uuid.NewString(),
+		},
+	}
+	rest := newRestyClient(restConfig.API, &http.Client{}, component.BuildInfo{})
+	httpmock.ActivateNonDefault(rest.GetClient())
+	defer httpmock.Reset()
+	httpmock.RegisterResponder(http.MethodGet, `=~^https:\/\/api\.cast\.ai/v1/audit.?`, httpmock.NewStringResponder(200, `{}`))
+
+	receiver := auditLogsReceiver{
+		logger:       zap.L(),
+		pageLimit:    10,
+		pollInterval: time.Hour,
+		webhook: &WebhookConfig{
+			ServerConfig: confighttp.ServerConfig{
+				Endpoint: "localhost:0",
+			},
+		},
+		webhookSecret: []byte("secret"),
+		telemetry:     componenttest.NewNopTelemetrySettings(),
+		wg:            &sync.WaitGroup{},
+		storage:       storage.NewInMemoryStorage(zap.L(), 0),
+		rest:          rest,
+	}
+	r.NoError(receiver.Start(ctx, componenttest.NewNopHost()))
+	r.NotNil(receiver.webhookServer)
+	r.NoError(receiver.Shutdown(ctx))
+}